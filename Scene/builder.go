@@ -3,6 +3,7 @@ package scene
 import (
 	"Raytracer/shapes"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 )
@@ -33,6 +34,45 @@ func NewBuilder(width, height, s int) (Camera, shapes.HitTable) {
 	case 7:
 		_, _ = fmt.Fprintln(os.Stdout, "Perlin sphere scene")
 		return buildPerlinSpheres(width, height)
+	case 8:
+		_, _ = fmt.Fprintln(os.Stdout, "Boxes scene")
+		return buildBoxes(width, height)
+	case 9:
+		_, _ = fmt.Fprintln(os.Stdout, "Smoke scene")
+		return buildSmoke(width, height)
+	case 10:
+		_, _ = fmt.Fprintln(os.Stdout, "Quadrics scene")
+		return buildQuadrics(width, height)
+	case 11:
+		_, _ = fmt.Fprintln(os.Stdout, "CSG scene")
+		return buildCSG(width, height)
+	case 12:
+		_, _ = fmt.Fprintln(os.Stdout, "SDF scene")
+		return buildSDF(width, height)
+	case 13:
+		_, _ = fmt.Fprintln(os.Stdout, "Terrain scene")
+		return buildTerrain(width, height)
+	case 14:
+		_, _ = fmt.Fprintln(os.Stdout, "Bezier patch scene")
+		return buildBezierPatches(width, height)
+	case 15:
+		_, _ = fmt.Fprintln(os.Stdout, "Hair scene")
+		return buildHair(width, height)
+	case 16:
+		_, _ = fmt.Fprintln(os.Stdout, "Metaballs scene")
+		return buildMetaballs(width, height)
+	case 17:
+		_, _ = fmt.Fprintln(os.Stdout, "Subdivision scene")
+		return buildSubdivision(width, height)
+	case 18:
+		_, _ = fmt.Fprintln(os.Stdout, "Motion blur scene")
+		return buildMotionBlur(width, height)
+	case 19:
+		_, _ = fmt.Fprintln(os.Stdout, "Particles scene")
+		return buildParticles(width, height)
+	case 20:
+		_, _ = fmt.Fprintln(os.Stdout, "Instances scene")
+		return buildInstances(width, height)
 	default:
 		_, _ = fmt.Fprintln(os.Stdout, "Build scene One")
 		return buildOne(width, height)
@@ -49,7 +89,7 @@ func buildWorldMetalSpheres(width, height int) (Camera, shapes.HitTableList) {
 
 	world := []shapes.HitTable{
 		shapes.Sphere{Center: shapes.Point3{Z: -1.0}, R: 0.5, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.8, G: 0.3, B: 0.3})}},
-		shapes.Sphere{Center: shapes.Point3{Y: -100.5, Z: -1.0}, R: 100, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.8, G: 0.8})}},
+		shapes.Plane{Point: shapes.Point3{Y: -0.5}, Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.8, G: 0.8})}},
 		shapes.Sphere{Center: shapes.Point3{X: 1.0, Y: 0, Z: -1.0}, R: 0.5, Material: shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 1.0}},
 		shapes.Sphere{Center: shapes.Point3{X: -1.0, Y: 0, Z: -1.0}, R: 0.5, Material: shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.8, B: 0.8}, Fuzz: 0.3}},
 	}
@@ -62,14 +102,14 @@ func buildOne(width, height int) (Camera, shapes.HitTableList) {
 	lookAt := shapes.Point3{Z: -1.0}
 	aperture := 0.0
 	distToFocus := 1.0
-	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus).WithShutter(0, 1)
 	c := shapes.Point3{X: -0.5, Y: 0.5, Z: -1.0}
 	y := rand.Float64() - 0.5
 	z := rand.Float64()
 	fmt.Fprintf(os.Stderr, "y: %.2f, z: %.2f\n", y, z)
 	world := []shapes.HitTable{
 		shapes.Sphere{Center: shapes.Point3{X: -1, Y: -0.5, Z: -4.6}, R: 0.5, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.1, G: 0.2, B: 0.5})}},
-		shapes.Sphere{Center: shapes.Point3{Y: -100.5, Z: -1.0}, R: 100, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.5, G: 0.5, B: 0.5})}},
+		shapes.Plane{Point: shapes.Point3{Y: -0.5}, Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.5, G: 0.5, B: 0.5})}},
 
 		shapes.MovingSphere{
 			Center0:  c,
@@ -94,7 +134,7 @@ func buildCustomScene(width, height int) (Camera, shapes.HitTableList) {
 
 	world := []shapes.HitTable{
 		shapes.Sphere{Center: shapes.Point3{X: -5, Y: 1}, R: 0.2, Material: shapes.Metal{Albedo: shapes.Color{R: 0.8}, Fuzz: 1.0}},
-		shapes.Sphere{Center: shapes.Point3{Y: -1000}, R: 1000, Material: shapes.Lambertian{Albedo: checker}},
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
 	}
 	world = append(world,
 		shapes.Sphere{
@@ -123,7 +163,7 @@ func buildWorldDielectrics(width, height int) (Camera, shapes.HitTableList) {
 
 	world := []shapes.HitTable{
 		shapes.Sphere{Center: shapes.Point3{X: -0.5, Y: 0.5, Z: -1.0}, R: 0.5, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.1, G: 0.2, B: 0.5})}},
-		shapes.Sphere{Center: shapes.Point3{Y: -100.5, Z: -1.0}, R: 100, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.5, G: 0.5, B: 0.5})}},
+		shapes.Plane{Point: shapes.Point3{Y: -0.5}, Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.5, G: 0.5, B: 0.5})}},
 		shapes.Sphere{Center: shapes.Point3{X: 1.0, Y: 0, Z: -1.0}, R: 0.5, Material: shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 1.0}},
 		/*Sphere{Center: Point3{X: -1.0, Y: 0, Z: -1.0}, R: 0.5, Material: Dielectric{1.5}},
 		Sphere{Center: Point3{X: -1.0, Y: 0, Z: -1.0}, R: -0.45, Material: Dielectric{1.5}},*/
@@ -138,7 +178,7 @@ func buildWorldFinalScene(width, height int) (Camera, shapes.HitTableList) {
 
 	maxSpheres := 500
 	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.3, G: 0.3, B: 0.3}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
-	world = append(world, shapes.Sphere{Center: shapes.Point3{Y: -1000.0}, R: 1000, Material: shapes.Lambertian{Albedo: checker}})
+	world = append(world, shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}})
 
 	for a := -11; a < 11 && len(world) < maxSpheres; a++ {
 		for b := -11; b < 11 && len(world) < maxSpheres; b++ {
@@ -203,7 +243,7 @@ func buildWorldLambertian(width, height int) (Camera, shapes.HitTableList) {
 
 	world := []shapes.HitTable{
 		shapes.Sphere{Center: shapes.Point3{Z: -1.0}, R: 0.5, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 1.0})}},
-		shapes.Sphere{Center: shapes.Point3{Y: -100.5, Z: -1.0}, R: 100, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{G: 1.0})}},
+		shapes.Plane{Point: shapes.Point3{Y: -0.5}, Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{G: 1.0})}},
 	}
 
 	return camera, shapes.HitTableList{Hits: world}
@@ -214,7 +254,7 @@ func buildMovingWorldFinalScene(width, height int) (Camera, shapes.HitTableList)
 	var world []shapes.HitTable
 
 	maxSpheres := 500
-	world = append(world, shapes.Sphere{Center: shapes.Point3{Y: -1000.0}, R: 1000, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.5, G: 0.5, B: 0.5})}})
+	world = append(world, shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.5, G: 0.5, B: 0.5})}})
 
 	for a := -11; a < 11 && len(world) < maxSpheres; a++ {
 		for b := -11; b < 11 && len(world) < maxSpheres; b++ {
@@ -273,7 +313,7 @@ func buildMovingWorldFinalScene(width, height int) (Camera, shapes.HitTableList)
 	lookAt := shapes.Point3{}
 	aperture := 0.1
 	distToFocus := 10.0
-	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus)
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 20, float64(width)/float64(height), aperture, distToFocus).WithShutter(0, 1)
 
 	return camera, shapes.HitTableList{Hits: world}
 }
@@ -304,9 +344,486 @@ func buildPerlinSpheres(width, height int) (Camera, shapes.HitTableList) {
 	pe := shapes.NewNoiseTexture(4)
 
 	world := []shapes.HitTable{
-		shapes.Sphere{Center: shapes.Point3{Y: -1000}, R: 1000, Material: shapes.Lambertian{Albedo: pe}},
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: pe}},
 		shapes.Sphere{Center: shapes.Point3{Y: 2}, R: 2, Material: shapes.Lambertian{Albedo: pe}},
 	}
 
 	return camera, shapes.HitTableList{Hits: world}
 }
+
+func buildBoxes(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+
+	world := []shapes.HitTable{
+		shapes.XZRect{X0: -10, X1: 10, Z0: -10, Z1: 10, Material: shapes.Lambertian{Albedo: checker}},
+		shapes.NewBox(shapes.Point3{X: -3, Z: -1}, shapes.Point3{X: -1, Y: 2, Z: 1}, shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.4, G: 0.2, B: 0.1})}),
+		// sloped roof on the first box
+		shapes.Quad{Q: shapes.Point3{X: -3.2, Y: 1.9, Z: -1.2}, U: shapes.Vec3{Z: 2.4}, V: shapes.Vec3{X: 1.2, Y: 0.9}, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.5, G: 0.1, B: 0.1})}},
+		shapes.Quad{Q: shapes.Point3{X: -0.8, Y: 1.9, Z: -1.2}, U: shapes.Vec3{X: -1.2, Y: 0.9}, V: shapes.Vec3{Z: 2.4}, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.5, G: 0.1, B: 0.1})}},
+		shapes.Translate(shapes.RotateY(shapes.NewBox(shapes.Point3{X: -0.5, Z: -0.5}, shapes.Point3{X: 0.5, Y: 1, Z: 0.5}, shapes.Metal{Albedo: shapes.Color{R: 0.7, G: 0.6, B: 0.5}, Fuzz: 0.1}), 30), shapes.Vec3{X: 0.5}),
+		shapes.NewBox(shapes.Point3{X: 2, Z: -1}, shapes.Point3{X: 3, Y: 1.5, Z: 0}, shapes.Dielectric{Ri: 1.5}),
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func buildSmoke(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	world := []shapes.HitTable{
+		shapes.XZRect{X0: -10, X1: 10, Z0: -10, Z1: 10, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.48, G: 0.83, B: 0.53})}},
+		shapes.NewConstantMedium(shapes.NewBox(shapes.Point3{X: -3, Z: -1}, shapes.Point3{X: -1, Y: 2, Z: 1}, nil), 1.0, shapes.NewSolidColor(shapes.Color{})),
+		shapes.NewConstantMedium(shapes.Sphere{Center: shapes.Point3{X: 1.5, Y: 1}, R: 1}, 2.0, shapes.NewSolidColor(shapes.Color{R: 1, G: 1, B: 1})),
+		shapes.Sphere{Center: shapes.Point3{X: 1.5, Y: 1}, R: 1, Material: shapes.Dielectric{Ri: 1.5}},
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func buildQuadrics(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		shapes.Cylinder{Center: shapes.Point3{X: -2, Z: -2}, R: 0.6, Height: 2, Capped: true, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.4, G: 0.2, B: 0.1})}},
+		shapes.Cylinder{Center: shapes.Point3{X: -2, Z: 1}, R: 0.6, Height: 1.5, Sweep: 270, Material: shapes.Metal{Albedo: shapes.Color{R: 0.7, G: 0.6, B: 0.5}, Fuzz: 0.1}},
+		shapes.Cone{Center: shapes.Point3{X: 1, Z: -1.5}, R: 0.8, Height: 2, Capped: true, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.1, G: 0.2, B: 0.5})}},
+		shapes.Torus{Center: shapes.Point3{X: 2, Y: 0.3, Z: 1.5}, R: 1, Tube: 0.3, Material: shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 0.2}},
+		shapes.Translate(shapes.RotateZ(shapes.Torus{R: 0.6, Tube: 0.15, Sweep: 300, Material: shapes.Dielectric{Ri: 1.5}}, 90), shapes.Vec3{Y: 0.75, Z: 3}),
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+// mustCSG panics when the operands of a CSG are rejected: the scenes are built from fixed shapes
+func mustCSG(c shapes.CSG, err error) shapes.CSG {
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+func buildCSG(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+	red := shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.7, G: 0.1, B: 0.1})}
+	blue := shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.1, G: 0.2, B: 0.5})}
+	gold := shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 0.1}
+
+	// sphere with a corner cut away
+	cutAway := mustCSG(shapes.NewDifference(
+		shapes.Sphere{Center: shapes.Point3{X: -1.5, Y: 1}, R: 1, Material: red},
+		shapes.NewBox(shapes.Point3{X: -1.5, Y: 1}, shapes.Point3{X: 0, Y: 2.5, Z: 1.5}, blue)))
+
+	// lens made of 2 overlapping spheres
+	lens := mustCSG(shapes.NewIntersection(
+		shapes.Sphere{Center: shapes.Point3{X: 1.5, Y: 1, Z: -0.6}, R: 1, Material: shapes.Dielectric{Ri: 1.5}},
+		shapes.Sphere{Center: shapes.Point3{X: 1.5, Y: 1, Z: 0.6}, R: 1, Material: shapes.Dielectric{Ri: 1.5}}))
+
+	// pipe: a cylinder drilled along its axis, merged with a ring
+	pipe := mustCSG(shapes.NewUnion(
+		mustCSG(shapes.NewDifference(
+			shapes.Cylinder{Center: shapes.Point3{Z: 2.5}, R: 0.5, Height: 1.5, Capped: true, Material: gold},
+			shapes.Cylinder{Center: shapes.Point3{Y: -0.1, Z: 2.5}, R: 0.35, Height: 1.7, Capped: true, Material: blue})),
+		shapes.Torus{Center: shapes.Point3{Y: 1.5, Z: 2.5}, R: 0.45, Tube: 0.1, Material: gold}))
+
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		cutAway,
+		lens,
+		pipe,
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func buildSDF(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+
+	// 2 spheres melting into each other
+	blob := shapes.SDF{
+		Distance: shapes.SDFSmoothUnion(shapes.SDFSphere(shapes.Point3{X: -2, Y: 1, Z: -0.5}, 0.8), shapes.SDFSphere(shapes.Point3{X: -2, Y: 1, Z: 0.7}, 0.6), 0.5),
+		Bounds:   shapes.AABB{Min: shapes.Vec3{X: -3, Z: -1.5}, Max: shapes.Vec3{X: -1, Y: 2, Z: 1.5}},
+		Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.7, G: 0.1, B: 0.1})},
+	}
+
+	// twisted rounded column
+	column := shapes.SDF{
+		Distance:  shapes.SDFTwist(shapes.SDFRound(shapes.SDFBox(shapes.Point3{X: 0, Y: 1, Z: 0}, shapes.Vec3{X: 0.3, Y: 0.9, Z: 0.3}), 0.1), 1.5),
+		Bounds:    shapes.AABB{Min: shapes.Vec3{X: -0.7, Z: -0.7}, Max: shapes.Vec3{X: 0.7, Y: 2.1, Z: 0.7}},
+		Material:  shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 0.1},
+		StepScale: 0.5,
+	}
+
+	// grid of small tori
+	tori := shapes.SDF{
+		Distance: shapes.SDFRepeat(shapes.SDFTorus(shapes.Point3{}, 0.25, 0.08), shapes.Vec3{X: 0.8, Z: 0.8}),
+		Bounds:   shapes.AABB{Min: shapes.Vec3{X: 1, Y: -0.1, Z: -2}, Max: shapes.Vec3{X: 3.4, Y: 0.1, Z: 2}},
+		Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.1, G: 0.2, B: 0.5})},
+	}
+
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		blob,
+		column,
+		shapes.Translate(tori, shapes.Vec3{Y: 0.1}),
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func buildTerrain(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	heights := shapes.PerlinHeights(shapes.NewPerlin(), 128, 128, 4, 4)
+	terrain := shapes.NewHeightfield(heights, 128, 128, shapes.Point3{X: -6, Y: -0.5, Z: -6}, shapes.Vec3{X: 12, Y: 2, Z: 12}, shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.4, G: 0.5, B: 0.3})})
+
+	world := []shapes.HitTable{
+		terrain,
+		shapes.Sphere{Center: shapes.Point3{Y: 2.5}, R: 0.8, Material: shapes.Dielectric{Ri: 1.5}},
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func mustMesh(m *shapes.Mesh, err error) *shapes.Mesh {
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func buildBezierPatches(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+
+	// a wavy sheet made of 2 x 2 patches sharing their edges
+	heights := [7][7]float64{
+		{0.0, 0.2, 0.6, 0.4, 0.2, 0.6, 0.8},
+		{0.2, 0.8, 1.4, 1.0, 0.6, 1.2, 1.0},
+		{0.4, 1.2, 1.8, 1.4, 0.8, 1.4, 1.2},
+		{0.2, 0.8, 1.2, 1.6, 1.2, 1.0, 0.6},
+		{0.0, 0.4, 0.6, 1.8, 2.0, 1.2, 0.4},
+		{0.2, 0.6, 0.8, 1.2, 1.4, 0.8, 0.2},
+		{0.4, 0.6, 0.6, 0.4, 0.2, 0.2, 0.0},
+	}
+	var patches []shapes.BezierPatch
+	for pj := 0; pj < 2; pj++ {
+		for pi := 0; pi < 2; pi++ {
+			var bp shapes.BezierPatch
+			for j := 0; j < 4; j++ {
+				for i := 0; i < 4; i++ {
+					x, z := 3*pi+i, 3*pj+j
+					bp.Points[4*j+i] = shapes.Point3{X: float64(x) - 3, Y: heights[z][x], Z: 3 - float64(z)}
+				}
+			}
+			patches = append(patches, bp)
+		}
+	}
+
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		mustMesh(shapes.NewBezierMesh(patches, 0.005, shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 0.2})),
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func buildHair(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+	fur := shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.6, G: 0.35, B: 0.15})}
+	grass := shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.6, B: 0.1})}
+
+	// a fur ball: strands growing out of the sphere and bending down
+	center := shapes.Point3{X: -1, Y: 1.2}
+	rnd := rand.New(rand.NewSource(1))
+	var strands []shapes.Curve
+	for i := 0; i < 30000; i++ {
+		n := shapes.RandomInUnitSphere(rnd).Unit()
+		root := center.Translate(n.Scale(0.9))
+		length := 0.3 + 0.1*rnd.Float64()
+		strands = append(strands, shapes.Curve{
+			Points: [4]shapes.Point3{
+				root,
+				root.Translate(n.Scale(length / 3)),
+				root.Translate(n.Scale(2 * length / 3)).Translate(shapes.Vec3{Y: -0.05}),
+				root.Translate(n.Scale(length)).Translate(shapes.Vec3{Y: -0.15}),
+			},
+			Width0:   0.01,
+			Width1:   0.002,
+			Material: fur,
+		})
+	}
+
+	// a patch of grass
+	for i := 0; i < 20000; i++ {
+		root := shapes.Point3{X: 1 + 3*rnd.Float64(), Z: -2 + 4*rnd.Float64()}
+		bend := shapes.Vec3{X: 0.2 * (rnd.Float64() - 0.5), Z: 0.2 * (rnd.Float64() - 0.5)}
+		h := 0.3 + 0.3*rnd.Float64()
+		strands = append(strands, shapes.Curve{
+			Points: [4]shapes.Point3{
+				root,
+				root.Translate(shapes.Vec3{Y: h / 3}),
+				root.Translate(bend.Scale(0.5)).Translate(shapes.Vec3{Y: 2 * h / 3}),
+				root.Translate(bend.Scale(2)).Translate(shapes.Vec3{Y: h}),
+			},
+			Width0:   0.02,
+			Width1:   0.001,
+			Material: grass,
+		})
+	}
+
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		shapes.Sphere{Center: center, R: 0.9, Material: fur},
+		shapes.NewCurves(strands),
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func buildMetaballs(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+
+	// a drop of liquid splitting in 2
+	liquid := shapes.Metaballs{
+		Balls: []shapes.Metaball{
+			{Center: shapes.Point3{X: -2, Y: 1.2, Z: -0.6}, R: 1.2, Weight: 1},
+			{Center: shapes.Point3{X: -2, Y: 1.0, Z: 0.6}, R: 1.0, Weight: 1},
+			{Center: shapes.Point3{X: -2, Y: 0.4, Z: 0}, R: 0.8, Weight: 0.8},
+		},
+		Threshold: 0.3,
+		Material:  shapes.Dielectric{Ri: 1.33},
+	}
+
+	// an organic blob with a dent carved by a negative ball
+	var balls []shapes.Metaball
+	for i := 0; i < 6; i++ {
+		a := float64(i) * math.Pi / 3
+		balls = append(balls, shapes.Metaball{Center: shapes.Point3{X: 1.5 + 0.6*math.Cos(a), Y: 0.8 + 0.3*math.Sin(2*a), Z: 0.6 * math.Sin(a)}, R: 0.8, Weight: 1})
+	}
+	balls = append(balls, shapes.Metaball{Center: shapes.Point3{X: 1.5, Y: 1.6}, R: 0.7, Weight: -1.5})
+	blob := shapes.Metaballs{
+		Balls:     balls,
+		Threshold: 0.4,
+		Material:  shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.7, G: 0.2, B: 0.3})},
+	}
+
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		liquid,
+		blob,
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+// unitCube returns the cube [0, 1]³ made of quads split into 2 triangles, like the loaders do
+func unitCube(m shapes.Material) *shapes.Mesh {
+	var positions []shapes.Point3
+	for i := 0; i < 8; i++ {
+		positions = append(positions, shapes.Point3{X: float64(i & 1), Y: float64(i >> 1 & 1), Z: float64(i >> 2 & 1)})
+	}
+	var indices []int32
+	for _, q := range [6][4]int32{{0, 2, 3, 1}, {4, 5, 7, 6}, {0, 1, 5, 4}, {2, 6, 7, 3}, {0, 4, 6, 2}, {1, 3, 7, 5}} {
+		indices = append(indices, q[0], q[1], q[2], q[0], q[2], q[3])
+	}
+	return shapes.NewMesh(positions, nil, nil, indices, []shapes.Material{m}, nil)
+}
+
+func buildSubdivision(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+
+	red := shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.7, G: 0.1, B: 0.1})}
+	blue := shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.1, G: 0.2, B: 0.7})}
+	gold := shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 0.1}
+
+	// the top face of the second cube keeps sharp edges
+	top := []shapes.Crease{
+		{A: 2, B: 3, Sharpness: math.Inf(1)}, {A: 3, B: 7, Sharpness: math.Inf(1)},
+		{A: 7, B: 6, Sharpness: math.Inf(1)}, {A: 6, B: 2, Sharpness: math.Inf(1)},
+	}
+
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		shapes.Translate(shapes.Scale(shapes.Subdivide(unitCube(red), shapes.CatmullClark, 3, nil), shapes.Vec3{X: 1.6, Y: 1.6, Z: 1.6}), shapes.Vec3{X: -3.5, Z: -0.8}),
+		shapes.Translate(shapes.Scale(shapes.Subdivide(unitCube(blue), shapes.CatmullClark, 3, top), shapes.Vec3{X: 1.6, Y: 1.6, Z: 1.6}), shapes.Vec3{X: -0.8, Z: -0.8}),
+		shapes.Translate(shapes.Scale(shapes.Subdivide(unitCube(gold), shapes.Loop, 3, nil), shapes.Vec3{X: 1.6, Y: 1.6, Z: 1.6}), shapes.Vec3{X: 1.8, Z: -0.8}),
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func buildMotionBlur(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus).WithShutter(0, 1)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+
+	// a box sliding and spinning during the exposure
+	box := shapes.NewBox(shapes.Point3{X: -0.5, Z: -0.5}, shapes.Point3{X: 0.5, Y: 1, Z: 0.5}, shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.7, G: 0.1, B: 0.1})})
+	spinning := shapes.NewMotion(box,
+		shapes.Keyframe{Time: 0, Translation: shapes.Vec3{X: -2.5, Z: -1}},
+		shapes.Keyframe{Time: 0.5, Translation: shapes.Vec3{X: -2.5, Z: 0}, Rotation: shapes.Vec3{Y: 45}},
+		shapes.Keyframe{Time: 1, Translation: shapes.Vec3{X: -2.5, Y: 0.5, Z: 1}, Rotation: shapes.Vec3{Y: 90}},
+	)
+
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		spinning,
+		shapes.MovingSphere{
+			Center0:  shapes.Point3{Y: 0.6},
+			Center1:  shapes.Point3{Y: 1.6},
+			R:        0.6,
+			Tm1:      1,
+			Material: shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 0.1},
+		},
+		shapes.Sphere{Center: shapes.Point3{X: 2.5, Y: 0.6}, R: 0.6, Material: shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.1, G: 0.2, B: 0.5})}},
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func buildParticles(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+	materials := []shapes.Material{
+		shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.7, G: 0.1, B: 0.1})},
+		shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.1, G: 0.2, B: 0.7})},
+		shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 0.2},
+	}
+
+	// a million particles swirling in a spiral galaxy
+	rnd := rand.New(rand.NewSource(1))
+	count := 1000000
+	centers := make([]shapes.Point3, count)
+	radii := make([]float64, count)
+	materialIndices := make([]int32, count)
+	for i := range centers {
+		arm := float64(rnd.Intn(3)) * 2 * math.Pi / 3
+		d := 4 * math.Sqrt(rnd.Float64())
+		a := arm + d*1.2 + 0.15*rnd.NormFloat64()
+		centers[i] = shapes.Point3{X: d * math.Cos(a), Y: 1.2 + 0.15*rnd.NormFloat64()*(1.2-d/4), Z: d * math.Sin(a)}
+		radii[i] = 0.004 + 0.006*rnd.Float64()
+		materialIndices[i] = int32(rnd.Intn(len(materials)))
+	}
+
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		shapes.NewSphereSet(centers, radii, materials, materialIndices),
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
+
+func mustInstances(in *shapes.Instances, err error) *shapes.Instances {
+	if err != nil {
+		panic(err)
+	}
+
+	return in
+}
+
+func buildInstances(width, height int) (Camera, shapes.HitTableList) {
+	lookFrom := shapes.Point3{X: 13, Y: 4.0, Z: 6.0}
+	lookAt := shapes.Point3{Y: 0.5}
+	aperture := 0.0
+	distToFocus := 1.0
+	camera := NewCamera(lookFrom, lookAt, shapes.Vec3{Y: 1.0}, 30, float64(width)/float64(height), aperture, distToFocus)
+
+	checker := shapes.CheckerTexture{Odd: shapes.NewSolidColor(shapes.Color{R: 0.2, G: 0.3, B: 0.1}), Even: shapes.NewSolidColor(shapes.Color{R: 0.9, G: 0.9, B: 0.9})}
+
+	// 3 rounded cubes centered on the bottom face, shared by 40000 instances
+	center := shapes.Vec3{X: -0.5, Z: -0.5}
+	prototypes := []shapes.HitTable{
+		shapes.Translate(shapes.Subdivide(unitCube(shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.7, G: 0.1, B: 0.1})}), shapes.CatmullClark, 3, nil), center),
+		shapes.Translate(shapes.Subdivide(unitCube(shapes.Lambertian{Albedo: shapes.NewSolidColor(shapes.Color{R: 0.1, G: 0.2, B: 0.7})}), shapes.CatmullClark, 3, nil), center),
+		shapes.Translate(shapes.Subdivide(unitCube(shapes.Metal{Albedo: shapes.Color{R: 0.8, G: 0.6, B: 0.2}, Fuzz: 0.1}), shapes.Loop, 3, nil), center),
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	var instances []shapes.Instance
+	for i := 0; i < 200; i++ {
+		for j := 0; j < 200; j++ {
+			s := 0.08 + 0.06*rnd.Float64()
+			k := shapes.Keyframe{
+				Translation: shapes.Vec3{X: -20 + 0.13*float64(i), Z: -14 + 0.13*float64(j)},
+				Rotation:    shapes.Vec3{Y: 90 * rnd.Float64()},
+				Scale:       shapes.Vec3{X: s, Y: s * (0.5 + 2*rnd.Float64()), Z: s},
+			}
+			instances = append(instances, shapes.Instance{Prototype: rnd.Intn(len(prototypes)), Matrix: k.Matrix()})
+		}
+	}
+
+	tm0, tm1 := camera.Shutter()
+	world := []shapes.HitTable{
+		shapes.Plane{Normal: shapes.Vec3{Y: 1}, Material: shapes.Lambertian{Albedo: checker}},
+		mustInstances(shapes.NewInstances(tm0, tm1, prototypes, instances)),
+	}
+
+	return camera, shapes.HitTableList{Hits: world}
+}
@@ -0,0 +1,151 @@
+package scene
+
+import (
+	"Raytracer/shapes"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// bhvNode is the pointer-based hierarchy the scenes were using before shapes.NewBVH (the former shapes.BhvNode),
+// kept as the reference of the benchmarks: the HitTables are sorted along an axis and split in 2 halves, and the
+// nodes are visited recursively, both children of a node being always intersected.
+type bhvNode struct {
+	left, right shapes.HitTable
+	box         shapes.AABB
+}
+
+func newBhvNode(hits []shapes.HitTable, tm0, tm1 float64, axis int) shapes.HitTable {
+	if len(hits) == 1 {
+		return hits[0]
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		_, a := hits[i].BoundingBox(tm0, tm1)
+		_, b := hits[j].BoundingBox(tm0, tm1)
+		return a.Min.GetAxis(axis) < b.Min.GetAxis(axis)
+	})
+
+	mid := len(hits) / 2
+	bn := bhvNode{left: newBhvNode(hits[:mid], tm0, tm1, (axis+1)%3), right: newBhvNode(hits[mid:], tm0, tm1, (axis+1)%3)}
+	_, bl := bn.left.BoundingBox(tm0, tm1)
+	_, br := bn.right.BoundingBox(tm0, tm1)
+	bn.box = shapes.NewAABB(*bl, *br)
+
+	return bn
+}
+
+func (bn bhvNode) BoundingBox(tm0, tm1 float64) (bool, *shapes.AABB) {
+	return true, &bn.box
+}
+
+func (bn bhvNode) Hit(r *shapes.Ray, tMin float64, tMax float64) (bool, *shapes.HitRecord) {
+	if hit, _ := bn.box.Hit(r, tMin, tMax); !hit {
+		return false, nil
+	}
+
+	hitLeft, hrLeft := bn.left.Hit(r, tMin, tMax)
+	hitRight, hrRight := bn.right.Hit(r, tMin, tMax)
+	switch {
+	case hitLeft && hitRight:
+		if hrRight.T > hrLeft.T {
+			return true, hrLeft
+		}
+		return true, hrRight
+	case hitLeft:
+		return true, hrLeft
+	case hitRight:
+		return true, hrRight
+	}
+
+	return false, nil
+}
+
+// newPointerBVH builds the reference hierarchy over the bounded HitTables of the world, the others are intersected
+// with every ray
+func newPointerBVH(tm0, tm1 float64, world shapes.HitTableList) shapes.HitTableList {
+	var bounded []shapes.HitTable
+	var res shapes.HitTableList
+	for _, h := range world.Hits {
+		if ok, _ := h.BoundingBox(tm0, tm1); ok {
+			bounded = append(bounded, h)
+		} else {
+			res.Hits = append(res.Hits, h)
+		}
+	}
+	if len(bounded) > 0 {
+		res.Hits = append(res.Hits, newBhvNode(bounded, tm0, tm1, 0))
+	}
+
+	return res
+}
+
+// builderScenes are the scenes of the builder having many HitTables or heavy ones
+var builderScenes = []struct {
+	name  string
+	build func(width, height int) (Camera, shapes.HitTableList)
+}{
+	{"final", buildWorldFinalScene},
+	{"moving final", buildMovingWorldFinalScene},
+	{"boxes", buildBoxes},
+	{"CSG", buildCSG},
+	{"motion blur", buildMotionBlur},
+	{"instances", buildInstances},
+}
+
+// cameraRays returns rays cast by the camera through random points of the image
+func cameraRays(c Camera, count int) []*shapes.Ray {
+	rnd := rand.New(rand.NewSource(1))
+	rays := make([]*shapes.Ray, count)
+	for i := range rays {
+		rays[i] = c.ray(rnd, rnd.Float64(), rnd.Float64())
+	}
+
+	return rays
+}
+
+func TestPointerBVHReference(t *testing.T) {
+	for _, s := range builderScenes {
+		t.Run(s.name, func(t *testing.T) {
+			camera, world := s.build(400, 200)
+			tm0, tm1 := camera.Shutter()
+			bvh := shapes.NewBVH(tm0, tm1, world)
+			ref := newPointerBVH(tm0, tm1, shapes.HitTableList{Hits: append([]shapes.HitTable(nil), world.Hits...)})
+
+			for _, r := range cameraRays(camera, 2000) {
+				hit, hr := bvh.Hit(r, 0.001, math.Inf(1))
+				wantHit, wantHR := ref.Hit(r, 0.001, math.Inf(1))
+				if hit != wantHit || (hit && hr.T != wantHR.T) {
+					t.Fatalf("ray %+v: hit %v %+v, reference %v %+v", r, hit, hr, wantHit, wantHR)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSceneHit compares the flat hierarchy of shapes.NewBVH with the former pointer-based one and with the
+// linear scan of the world on the builder scenes
+func BenchmarkSceneHit(b *testing.B) {
+	for _, s := range builderScenes {
+		camera, world := s.build(400, 200)
+		tm0, tm1 := camera.Shutter()
+		rays := cameraRays(camera, 1024)
+
+		worlds := []struct {
+			name  string
+			world shapes.HitTable
+		}{
+			{"bvh", shapes.NewBVH(tm0, tm1, world)},
+			{"pointer", newPointerBVH(tm0, tm1, shapes.HitTableList{Hits: append([]shapes.HitTable(nil), world.Hits...)})},
+			{"list", world},
+		}
+		for _, w := range worlds {
+			b.Run(s.name+"/"+w.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					w.world.Hit(rays[i%len(rays)], 0.001, math.Inf(1))
+				}
+			})
+		}
+	}
+}
@@ -15,6 +15,8 @@ type Camera struct {
 	u, v       shapes.Vec3
 	lensRadius float64
 	rnd        shapes.Rnd
+	// the shutter is open between time0 and time1
+	time0, time1 float64
 }
 
 // NewCamera computes the parameters necessary for the Camera...
@@ -34,18 +36,34 @@ func NewCamera(lookFrom shapes.Point3, lookAt shapes.Point3, vup shapes.Vec3, vf
 	horizontal := u.Scale(2 * halfWidth * focusDist)
 	vertical := v.Scale(2 * halfHeight * focusDist)
 	
-	return Camera{origin, lowerLeftCorner, horizontal, vertical, u, v, aperture / 2.0, rand.New(rand.NewSource(time.Now().UnixNano()))}
+	return Camera{origin, lowerLeftCorner, horizontal, vertical, u, v, aperture / 2.0, rand.New(rand.NewSource(time.Now().UnixNano())), 0, 0}
+}
+
+// WithShutter returns the camera with its shutter open from the time open to the time close: every ray is cast at
+// a random time within the interval, which blurs the moving objects
+func (c Camera) WithShutter(open, close float64) Camera {
+	c.time0, c.time1 = open, close
+	return c
+}
+
+// Shutter returns the interval during which the shutter is open
+func (c Camera) Shutter() (float64, float64) {
+	return c.time0, c.time1
 }
 
 func (c Camera) ray(rnd shapes.Rnd, u, v float64) *shapes.Ray {
 	d := c.llc.Translate(c.horizontal.Scale(u)).Translate(c.vertical.Scale(v)).Sub(c.origin)
+	t := c.time0
+	if c.time1 != c.time0 {
+		t += rnd.Float64() * (c.time1 - c.time0)
+	}
 	
 	if c.lensRadius <= 0 {
-		return &shapes.Ray{Origin: c.origin, Dir: d, Rnd: rnd}
+		return &shapes.Ray{Origin: c.origin, Dir: d, Rnd: rnd, Time: t}
 	}
 	
 	rd := shapes.RandomInUnitDisk(rnd).Scale(c.lensRadius)
 	offset := c.u.Scale(rd.X).Add(c.v.Scale(rd.Y))
 	
-	return &shapes.Ray{Origin: c.origin.Translate(offset), Dir: d.Sub(offset), Rnd: rnd}
+	return &shapes.Ray{Origin: c.origin.Translate(offset), Dir: d.Sub(offset), Rnd: rnd, Time: t}
 }
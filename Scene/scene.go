@@ -24,8 +24,11 @@ type Scene struct {
 	world         shapes.HitTable
 }
 
+// NewScene creates the scene to render the world seen from the camera. The world is organized in a bounding volume
+// hierarchy (over the shutter interval of the camera) so that the rays are not intersected with every object.
 func NewScene(w, h int, rpp []int, c Camera, world shapes.HitTable) *Scene{
-	return &Scene{width: w, height: h, raysPerPixel: rpp, Camera: c, world: world}
+	tm0, tm1 := c.Shutter()
+	return &Scene{width: w, height: h, raysPerPixel: rpp, Camera: c, world: shapes.NewBVH(tm0, tm1, world)}
 }
 // pixel is an internal type which represents the pixel to be processed
 //	x,y are the coordinates
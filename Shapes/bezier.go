@@ -0,0 +1,319 @@
+package shapes
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// bezierMaxSegments limits the number of segments a patch is split into along u or v
+const bezierMaxSegments = 64
+
+// BezierPatch is a bicubic Bézier surface defined by a 4 x 4 grid of control points: Points[4*j + i] is the i-th
+// point along u of the j-th row along v. The surface goes through the 4 corner points.
+type BezierPatch struct {
+	Points [16]Point3
+}
+
+// bernstein returns the cubic Bernstein polynomials at t and their derivatives
+func bernstein(t float64) ([4]float64, [4]float64) {
+	s := 1 - t
+	return [4]float64{s * s * s, 3 * t * s * s, 3 * t * t * s, t * t * t},
+		[4]float64{-3 * s * s, 3*s*s - 6*t*s, 6*t*s - 3*t*t, 3 * t * t}
+}
+
+// evaluate returns the point of the surface at (u, v) and the partial derivatives along u and v
+func (bp BezierPatch) evaluate(u, v float64) (Point3, Vec3, Vec3) {
+	bu, du := bernstein(u)
+	bv, dv := bernstein(v)
+
+	var p, pu, pv Vec3
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			c := bp.Points[4*j+i].Vec3()
+			p = p.Add(c.Scale(bu[i] * bv[j]))
+			pu = pu.Add(c.Scale(du[i] * bv[j]))
+			pv = pv.Add(c.Scale(bu[i] * dv[j]))
+		}
+	}
+
+	return Point3{X: p.X, Y: p.Y, Z: p.Z}, pu, pv
+}
+
+// Point returns the point of the surface at (u, v)
+func (bp BezierPatch) Point(u, v float64) Point3 {
+	p, _, _ := bp.evaluate(u, v)
+	return p
+}
+
+// Normal returns the normal of the surface at (u, v), along Cross(dP/du, dP/dv)
+func (bp BezierPatch) Normal(u, v float64) Vec3 {
+	_, pu, pv := bp.evaluate(u, v)
+	n := Cross(pu, pv)
+	if n.Length() > 1e-12 {
+		return n.Unit()
+	}
+
+	// an edge of the patch collapsed into a point (e.g. the top of the teapot lid): the normal is the limit
+	// taken from inside the patch
+	_, pu, pv = bp.evaluate(u+(0.5-u)*1e-4, v+(0.5-v)*1e-4)
+	return Cross(pu, pv).Unit()
+}
+
+// segments returns the number of segments needed along u and v so that the triangles are at most tolerance away
+// from the surface. It is bound by the second differences of the control points.
+func (bp BezierPatch) segments(tolerance float64) (int, int) {
+	var mu, mv float64
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 2; i++ {
+			mu = math.Max(mu, bp.Points[4*j+i+2].Sub(bp.Points[4*j+i+1]).Sub(bp.Points[4*j+i+1].Sub(bp.Points[4*j+i])).Length())
+			mv = math.Max(mv, bp.Points[4*(i+2)+j].Sub(bp.Points[4*(i+1)+j]).Sub(bp.Points[4*(i+1)+j].Sub(bp.Points[4*i+j])).Length())
+		}
+	}
+
+	n := func(m float64) int {
+		// clamped before the conversion, which is undefined for the values out of the range of int
+		return clampInt(int(math.Min(math.Ceil(math.Sqrt(0.75*m/tolerance)), bezierMaxSegments)), 1, bezierMaxSegments)
+	}
+
+	return n(mu), n(mv)
+}
+
+// edgeKey returns a key identifying the edge made of the 4 control points whatever their order, and whether
+// the edge is collapsed into a point
+func edgeKey(a, b, c, d Point3) ([4]Point3, bool) {
+	key := [4]Point3{a, b, c, d}
+	if a.X > d.X || a.X == d.X && (a.Y > d.Y || a.Y == d.Y && a.Z > d.Z) {
+		key = [4]Point3{d, c, b, a}
+	}
+
+	return key, a == b && b == c && c == d
+}
+
+// NewBezierMesh tessellates the patches into a mesh whose triangles are at most tolerance (in world units) away
+// from the surface. Each patch is split into a grid whose size depends on its curvature, and the patches sharing
+// an edge use the same number of segments along it so that there is no crack between them.
+// The normals are the ones of the surface and u, v are the parameters of each patch. m may be nil.
+// An error is returned when the tolerance is not positive.
+func NewBezierMesh(patches []BezierPatch, tolerance float64, m Material) (*Mesh, error) {
+	if !(tolerance > 0) {
+		return nil, fmt.Errorf("bezier: invalid tolerance %v", tolerance)
+	}
+	if m == nil {
+		m = DefaultMeshMaterial
+	}
+
+	// every patch has a u and a v dimension (2i and 2i+1): the dimensions of the patches sharing an edge are
+	// merged and get the number of segments of the most curved one
+	parent := make([]int, 2*len(patches))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	edges := map[[4]Point3]int{}
+	for i, bp := range patches {
+		p := bp.Points
+		for _, e := range []struct {
+			a, b, c, d int
+			dim        int
+		}{
+			{0, 1, 2, 3, 2 * i}, {12, 13, 14, 15, 2 * i},
+			{0, 4, 8, 12, 2*i + 1}, {3, 7, 11, 15, 2*i + 1},
+		} {
+			key, collapsed := edgeKey(p[e.a], p[e.b], p[e.c], p[e.d])
+			if collapsed {
+				continue
+			}
+			if other, ok := edges[key]; ok {
+				parent[find(e.dim)] = find(other)
+			} else {
+				edges[key] = e.dim
+			}
+		}
+	}
+
+	segments := make([]int, len(parent))
+	for i, bp := range patches {
+		nu, nv := bp.segments(tolerance)
+		segments[find(2*i)] = maxInt(segments[find(2*i)], nu)
+		segments[find(2*i+1)] = maxInt(segments[find(2*i+1)], nv)
+	}
+
+	var (
+		positions []Point3
+		normals   []Vec3
+		uvs       []UV
+		indices   []int32
+	)
+	for i, bp := range patches {
+		nu, nv := segments[find(2*i)], segments[find(2*i+1)]
+		first := int32(len(positions))
+		for j := 0; j <= nv; j++ {
+			for k := 0; k <= nu; k++ {
+				u, v := float64(k)/float64(nu), float64(j)/float64(nv)
+				positions = append(positions, bp.Point(u, v))
+				normals = append(normals, bp.Normal(u, v))
+				uvs = append(uvs, UV{U: u, V: v})
+			}
+		}
+
+		vertex := func(k, j int) int32 {
+			return first + int32(j*(nu+1)+k)
+		}
+		for j := 0; j < nv; j++ {
+			for k := 0; k < nu; k++ {
+				a, b, c, d := vertex(k, j), vertex(k+1, j), vertex(k+1, j+1), vertex(k, j+1)
+				for _, tri := range [2][3]int32{{a, b, c}, {a, c, d}} {
+					// the triangles touching a collapsed edge are degenerate
+					if positions[tri[0]] == positions[tri[1]] || positions[tri[1]] == positions[tri[2]] || positions[tri[2]] == positions[tri[0]] {
+						continue
+					}
+					indices = append(indices, tri[:]...)
+				}
+			}
+		}
+	}
+
+	return NewMesh(positions, normals, uvs, indices, []Material{m}, nil), nil
+}
+
+// LoadBezierPatches reads the bicubic patches of a patch file, in one of the 2 formats used for the Utah teapot:
+//   - the number of patches, a line of 16 vertex indices (starting at 1) per patch, the number of vertices and
+//     a line of 3 coordinates per vertex (values separated by commas or spaces)
+//   - the BPT format: the number of patches, then for each patch a line with its degrees ("3 3") followed by 16
+//     lines of 3 coordinates
+func LoadBezierPatches(path string) ([]BezierPatch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	type patchLine struct {
+		line   int
+		fields []string
+	}
+	var lines []patchLine
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+		if len(fields) > 0 {
+			lines = append(lines, patchLine{line: line, fields: fields})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	next := 0
+	read := func(n int) ([]string, int, error) {
+		if next >= len(lines) {
+			return nil, 0, fmt.Errorf("%v: unexpected end of file", path)
+		}
+		l := lines[next]
+		next++
+		if len(l.fields) != n {
+			return nil, 0, fmt.Errorf("%v:%d: expected %d values, got %d", path, l.line, n, len(l.fields))
+		}
+		return l.fields, l.line, nil
+	}
+	readPoint := func() (Point3, error) {
+		fields, line, err := read(3)
+		if err != nil {
+			return Point3{}, err
+		}
+		c, err := parseFloats(fields, 3)
+		if err != nil {
+			return Point3{}, fmt.Errorf("%v:%d: %v", path, line, err)
+		}
+		return Point3{X: c[0], Y: c[1], Z: c[2]}, nil
+	}
+	readCount := func() (int, error) {
+		fields, line, err := read(1)
+		if err != nil {
+			return 0, err
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%v:%d: invalid count %v", path, line, fields[0])
+		}
+		return n, nil
+	}
+
+	count, err := readCount()
+	if err != nil {
+		return nil, err
+	}
+	patches := make([]BezierPatch, count)
+
+	// BPT
+	if next < len(lines) && len(lines[next].fields) == 2 {
+		for i := range patches {
+			fields, line, err := read(2)
+			if err != nil {
+				return nil, err
+			}
+			if fields[0] != "3" || fields[1] != "3" {
+				return nil, fmt.Errorf("%v:%d: unsupported degrees %v %v", path, line, fields[0], fields[1])
+			}
+			for k := range patches[i].Points {
+				if patches[i].Points[k], err = readPoint(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		return patches, nil
+	}
+
+	indices := make([][16]int, count)
+	lineNumbers := make([]int, count)
+	for i := range indices {
+		fields, line, err := read(16)
+		if err != nil {
+			return nil, err
+		}
+		lineNumbers[i] = line
+		for k, s := range fields {
+			if indices[i][k], err = strconv.Atoi(s); err != nil {
+				return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+			}
+		}
+	}
+
+	vertexCount, err := readCount()
+	if err != nil {
+		return nil, err
+	}
+	vertices := make([]Point3, vertexCount)
+	for i := range vertices {
+		if vertices[i], err = readPoint(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range patches {
+		for k, index := range indices[i] {
+			if index < 1 || index > vertexCount {
+				return nil, fmt.Errorf("%v:%d: index %d out of range", path, lineNumbers[i], index)
+			}
+			patches[i].Points[k] = vertices[index-1]
+		}
+	}
+
+	return patches, nil
+}
@@ -0,0 +1,53 @@
+package shapes
+
+import (
+	"math"
+	"testing"
+)
+
+// curvedPatch returns a patch bulging in its middle
+func curvedPatch() BezierPatch {
+	var bp BezierPatch
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			y := 0.0
+			if (i == 1 || i == 2) && (j == 1 || j == 2) {
+				y = 1
+			}
+			bp.Points[4*j+i] = Point3{X: float64(i), Y: y, Z: float64(j)}
+		}
+	}
+
+	return bp
+}
+
+func TestNewBezierMeshTolerance(t *testing.T) {
+	patches := []BezierPatch{curvedPatch()}
+
+	for _, tolerance := range []float64{0, -0.01, math.NaN()} {
+		if _, err := NewBezierMesh(patches, tolerance, nil); err == nil {
+			t.Errorf("tolerance %v: expected an error", tolerance)
+		}
+	}
+
+	coarse, err := NewBezierMesh(patches, 0.1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fine, err := NewBezierMesh(patches, 0.001, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coarse.TriangleCount() >= fine.TriangleCount() {
+		t.Errorf("%d triangles at 0.1, %d at 0.001: want more with the smaller tolerance", coarse.TriangleCount(), fine.TriangleCount())
+	}
+
+	// the number of segments is bound whatever the tolerance
+	finest, err := NewBezierMesh(patches, 1e-320, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2 * bezierMaxSegments * bezierMaxSegments; finest.TriangleCount() != want {
+		t.Errorf("%d triangles at 1e-320, want %d", finest.TriangleCount(), want)
+	}
+}
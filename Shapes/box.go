@@ -2,8 +2,6 @@ package shapes
 
 import (
 	"math"
-	"math/rand"
-	"time"
 )
 
 // AABB (axis-aligned bounding box)
@@ -19,9 +17,9 @@ func NewAABB(box0, box1 AABB) AABB {
 	}
 	
 	max := Vec3{
-		X: math.Max(box0.Min.X, box1.Min.X),
-		Y: math.Max(box0.Min.Y, box1.Min.Y),
-		Z: math.Max(box0.Min.Z, box1.Min.Z),
+		X: math.Max(box0.Max.X, box1.Max.X),
+		Y: math.Max(box0.Max.Y, box1.Max.Y),
+		Z: math.Max(box0.Max.Z, box1.Max.Z),
 	}
 	
 	return AABB{Min: min, Max: max}
@@ -47,82 +45,157 @@ func (ab AABB) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
 	return true, nil
 }
 
+// BhvNode is a bounding volume hierarchy over a range of HitTables.
+//
+// Deprecated: use NewBVH, which returns the whole hierarchy.
 type BhvNode struct {
-	left, right HitTable
-	box         AABB
+	bvh *BVH
 }
 
+// NewBVHNode builds the bounding volume hierarchy over the HitTables hl.Hits[start:end].
+//
+// Deprecated: use NewBVH.
 func NewBVHNode(start, end int32, tm0, tm1 float64, hl *HitTableList) BhvNode {
-	var bn BhvNode
-	rand.Seed(time.Now().UnixNano())
-	axis := int(rand.Int63n(2))
-	
-	os := start - end
-	byAxis := func(a, b *HitTable) bool {
-		aa := *a
-		bb := *b
-		_, boxA := aa.BoundingBox(0, 0)
-		_, boxB := bb.BoundingBox(0, 0)
-		return boxA.Min.GetAxis(axis) < boxB.Min.GetAxis(axis)
-	}
-	
-	switch os {
-	case 1:
-		bn.left = hl.Hits[start]
-		bn.right = hl.Hits[start]
-	case 2:
-		bn.left = hl.Hits[start]
-		bn.right = hl.Hits[start+1]
-		if hl.BoxCompare(bn.left, bn.right, axis) {
-			bn.left = hl.Hits[start+1]
-			bn.right = hl.Hits[start]
-		}
-	default:
-		OrderedBy(byAxis).Sort(hl.Hits)
-		mid := start + (os / 2)
-		bn.left = NewBVHNode(start, mid, tm0, tm1, hl)
-		bn.right = NewBVHNode(mid, end, tm0, tm1, hl)
-		
+	return BhvNode{bvh: NewBVH(tm0, tm1, hl.Hits[start:end]...)}
+}
+
+func (bn BhvNode) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	return bn.bvh.BoundingBox(tm0, tm1)
+}
+
+func (bn BhvNode) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
+	return bn.bvh.Hit(r, tMin, tMax)
+}
+
+// Box is a closed axis-aligned cuboid made of six rectangles
+type Box struct {
+	Min, Max Point3
+	sides    HitTableList
+}
+
+// NewBox returns the box having p0 and p1 as opposite corners. Every face has its normal pointing outward and
+// its own u, v coordinates.
+func NewBox(p0, p1 Point3, m Material) Box {
+	min := Point3{X: math.Min(p0.X, p1.X), Y: math.Min(p0.Y, p1.Y), Z: math.Min(p0.Z, p1.Z)}
+	max := Point3{X: math.Max(p0.X, p1.X), Y: math.Max(p0.Y, p1.Y), Z: math.Max(p0.Z, p1.Z)}
+
+	sides := []HitTable{
+		XYRect{X0: min.X, X1: max.X, Y0: min.Y, Y1: max.Y, K: max.Z, Material: m},
+		FlipFace{HitTable: XYRect{X0: min.X, X1: max.X, Y0: min.Y, Y1: max.Y, K: min.Z, Material: m}},
+		XZRect{X0: min.X, X1: max.X, Z0: min.Z, Z1: max.Z, K: max.Y, Material: m},
+		FlipFace{HitTable: XZRect{X0: min.X, X1: max.X, Z0: min.Z, Z1: max.Z, K: min.Y, Material: m}},
+		YZRect{Y0: min.Y, Y1: max.Y, Z0: min.Z, Z1: max.Z, K: max.X, Material: m},
+		FlipFace{HitTable: YZRect{Y0: min.Y, Y1: max.Y, Z0: min.Z, Z1: max.Z, K: min.X, Material: m}},
 	}
-	
-	_, bl := bn.left.BoundingBox(tm0, tm1)
-	_, br := bn.right.BoundingBox(tm0, tm1)
-	
-	bn.box = NewAABB(*bl, *br)
-	return bn
+
+	return Box{Min: min, Max: max, sides: HitTableList{Hits: sides}}
 }
 
+func (b Box) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return b.sides.Hit(r, tMin, tMax)
+}
 
-func (bn BhvNode) BoundingBox(tm0, tm1  float64) (bool, *AABB) {
-	return true, &bn.box
+func (b Box) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	return true, &AABB{Min: b.Min.Vec3(), Max: b.Max.Vec3()}
 }
 
-func (bn BhvNode) Hit(r *Ray, tMin float64, tMax float64) (bool, *HitRecord) {
-	if hit, _ := bn.box.Hit(r, tMin, tMax); !hit {
-		return false, nil
+// pointsAABB returns the smallest box containing all the points. Flat axes are padded (see rectPadding)
+// so that the box can still be hit.
+func pointsAABB(points ...Point3) AABB {
+	inf := math.Inf(1)
+	box := AABB{Min: Vec3{X: inf, Y: inf, Z: inf}, Max: Vec3{X: -inf, Y: -inf, Z: -inf}}
+	for _, p := range points {
+		box.Min = Vec3{X: math.Min(box.Min.X, p.X), Y: math.Min(box.Min.Y, p.Y), Z: math.Min(box.Min.Z, p.Z)}
+		box.Max = Vec3{X: math.Max(box.Max.X, p.X), Y: math.Max(box.Max.Y, p.Y), Z: math.Max(box.Max.Z, p.Z)}
 	}
-	
-	hitLeft, hrLeft := bn.left.Hit(r, tMin, tMax)
-	hitRight, hrRight := bn.right.Hit(r, tMin, tMax)
-	if !hitLeft && !hitRight {
-		return false, nil
+
+	return box.padded()
+}
+
+// padded returns the box with its flat axes (if any) enlarged by rectPadding
+func (ab AABB) padded() AABB {
+	if ab.Max.X-ab.Min.X < rectPadding {
+		ab.Min.X, ab.Max.X = ab.Min.X-rectPadding, ab.Max.X+rectPadding
 	}
-	
-	if hitLeft && hitRight {
-		if hrRight.T > hrLeft.T {
-			return true, hrLeft
+	if ab.Max.Y-ab.Min.Y < rectPadding {
+		ab.Min.Y, ab.Max.Y = ab.Min.Y-rectPadding, ab.Max.Y+rectPadding
+	}
+	if ab.Max.Z-ab.Min.Z < rectPadding {
+		ab.Min.Z, ab.Max.Z = ab.Min.Z-rectPadding, ab.Max.Z+rectPadding
+	}
+
+	return ab
+}
+
+// hitInv is a faster version of Hit (no allocation) taking the inverse of the ray direction, which is computed
+// once per ray when traversing a hierarchy of boxes
+func (ab AABB) hitInv(origin, invDir Vec3, tMin, tMax float64) bool {
+	for a := 0; a < 3; a++ {
+		invD := invDir.GetAxis(a)
+		oa := origin.GetAxis(a)
+		t0 := (ab.Min.GetAxis(a) - oa) * invD
+		t1 := (ab.Max.GetAxis(a) - oa) * invD
+		if invD < 0.0 {
+			t0, t1 = t1, t0
+		}
+		// written this way a NaN (ray parallel to and on the slab) leaves the interval untouched
+		if t0 > tMin {
+			tMin = t0
+		}
+		if t1 < tMax {
+			tMax = t1
+		}
+		if tMax < tMin {
+			return false
 		}
-		
-		return true, hrRight
 	}
-	
-	if hitLeft {
-		return true, hrLeft
+
+	return true
+}
+
+// union returns the smallest box containing both boxes
+func (ab AABB) union(b AABB) AABB {
+	return AABB{
+		Min: Vec3{X: math.Min(ab.Min.X, b.Min.X), Y: math.Min(ab.Min.Y, b.Min.Y), Z: math.Min(ab.Min.Z, b.Min.Z)},
+		Max: Vec3{X: math.Max(ab.Max.X, b.Max.X), Y: math.Max(ab.Max.Y, b.Max.Y), Z: math.Max(ab.Max.Z, b.Max.Z)},
 	}
-	
-	if hitRight {
-		return true, hrRight
+}
+
+// area returns the surface area of the box
+func (ab AABB) area() float64 {
+	d := ab.Max.Sub(ab.Min)
+	return 2 * (d.X*d.Y + d.Y*d.Z + d.Z*d.X)
+}
+
+// centroid returns the center of the box
+func (ab AABB) centroid() Vec3 {
+	return ab.Min.Add(ab.Max).Scale(0.5)
+}
+
+func (b Box) Spans(r *Ray) []Span {
+	return surfaceSpans(b, r)
+}
+
+// clip returns the part of the segment ]tMin, tMax[ of the ray which is inside the box
+func (ab AABB) clip(r *Ray, tMin, tMax float64) (bool, float64, float64) {
+	for a := 0; a < 3; a++ {
+		invD := 1.0 / r.Dir.GetAxis(a)
+		oa := r.Origin.Vec3().GetAxis(a)
+		t0 := (ab.Min.GetAxis(a) - oa) * invD
+		t1 := (ab.Max.GetAxis(a) - oa) * invD
+		if invD < 0.0 {
+			t0, t1 = t1, t0
+		}
+		if t0 > tMin {
+			tMin = t0
+		}
+		if t1 < tMax {
+			tMax = t1
+		}
+		if tMax < tMin {
+			return false, 0, 0
+		}
 	}
-	
-	return false, nil
+
+	return true, tMin, tMax
 }
@@ -0,0 +1,447 @@
+package shapes
+
+import (
+	"math"
+)
+
+const (
+	// bvhLeafSize is the number of primitives under which a node of a primitiveBVH is not split
+	bvhLeafSize = 2
+
+	// bvhMaxLeafSize is the maximum number of primitives held by a leaf (the surface area heuristic may prefer
+	// a leaf to a split up to that size)
+	bvhMaxLeafSize = 8
+
+	// bvhBins is the number of bins the centroids are sorted into to evaluate the surface area heuristic
+	bvhBins = 12
+
+	// bvhTraversalCost is the cost of visiting a node relative to the cost of intersecting a primitive
+	bvhTraversalCost = 0.5
+
+	// bvhRebuildRatio is how much more expensive than when it was built (according to the surface area heuristic)
+	// a refitted hierarchy may get before it has to be rebuilt
+	bvhRebuildRatio = 1.5
+)
+
+// primitives is implemented by the shapes made of many primitives (triangles of a mesh...) addressed by index
+type primitives interface {
+	hitPrimitive(i int, r *Ray, tMin, tMax float64) (bool, *HitRecord)
+}
+
+// bvhNode is a node of a flattened bounding volume hierarchy.
+// A leaf (count > 0) holds the primitives indices[offset:offset+count]. The left child of an inner node is
+// stored right after it and offset is the index of the right child. axis is the axis along which the
+// primitives of an inner node were split (the left child holds the smaller ones).
+type bvhNode struct {
+	box    AABB
+	offset int32
+	count  int32
+	axis   uint8
+}
+
+// primitiveBVH is a bounding volume hierarchy over primitives identified by their index, stored in a flat
+// array (no pointer) and traversed with an explicit stack. The children are visited front to back, so that
+// the closest hit is usually found first and the boxes behind it are skipped.
+type primitiveBVH struct {
+	nodes   []bvhNode
+	indices []int32
+	cost    float64 // cost of the hierarchy when it was built (see sahCost)
+}
+
+// newPrimitiveBVH builds the hierarchy over the primitives having the given bounding boxes
+func newPrimitiveBVH(boxes []AABB) primitiveBVH {
+	b := primitiveBVH{indices: make([]int32, len(boxes))}
+	if len(boxes) == 0 {
+		return b
+	}
+
+	bb := bvhBuilder{indices: b.indices, boxes: append([]AABB(nil), boxes...), centroids: make([]Vec3, len(boxes))}
+	for i := range boxes {
+		bb.indices[i] = int32(i)
+		bb.centroids[i] = boxes[i].centroid()
+	}
+
+	b.nodes = make([]bvhNode, 0, 2*len(boxes)/bvhLeafSize+1)
+	b.build(&bb, 0, len(boxes))
+	b.cost = b.sahCost()
+	return b
+}
+
+// bvhBuilder holds the primitives being organized in a hierarchy. The boxes and centroids are reordered along
+// with the indices so that they are read sequentially, which matters for millions of primitives.
+type bvhBuilder struct {
+	indices   []int32
+	boxes     []AABB
+	centroids []Vec3
+}
+
+func (bb *bvhBuilder) swap(i, j int) {
+	bb.indices[i], bb.indices[j] = bb.indices[j], bb.indices[i]
+	bb.boxes[i], bb.boxes[j] = bb.boxes[j], bb.boxes[i]
+	bb.centroids[i], bb.centroids[j] = bb.centroids[j], bb.centroids[i]
+}
+
+// bvhBin accumulates the primitives whose centroid falls in a bin
+type bvhBin struct {
+	count int
+	box   AABB
+}
+
+func (bin *bvhBin) add(count int, box AABB) {
+	if bin.count == 0 {
+		bin.box = box
+	} else {
+		bin.box = bin.box.union(box)
+	}
+	bin.count += count
+}
+
+// build creates the node for the primitives [start, end[ of the builder (and its children) and returns its index.
+// The primitives are split along the axis where their centroids spread the most, at the position minimizing the
+// surface area heuristic: the centroids are sorted into bvhBins bins and every boundary between 2 bins is
+// evaluated. A node is split in 2 halves when the heuristic cannot separate its primitives, or when their centroids
+// are all the same (so that the leaves never hold more than bvhMaxLeafSize primitives).
+func (b *primitiveBVH) build(bb *bvhBuilder, start, end int) int32 {
+	node := int32(len(b.nodes))
+	b.nodes = append(b.nodes, bvhNode{})
+
+	box := bb.boxes[start]
+	cb := AABB{Min: bb.centroids[start], Max: bb.centroids[start]}
+	for i := start + 1; i < end; i++ {
+		box = box.union(bb.boxes[i])
+		c := bb.centroids[i]
+		cb.Min = Vec3{X: math.Min(cb.Min.X, c.X), Y: math.Min(cb.Min.Y, c.Y), Z: math.Min(cb.Min.Z, c.Z)}
+		cb.Max = Vec3{X: math.Max(cb.Max.X, c.X), Y: math.Max(cb.Max.Y, c.Y), Z: math.Max(cb.Max.Z, c.Z)}
+	}
+
+	n := end - start
+	axis := longestAxis(cb)
+	lo, extent := cb.Min.GetAxis(axis), cb.Max.GetAxis(axis)-cb.Min.GetAxis(axis)
+
+	leaf := func() int32 {
+		b.nodes[node] = bvhNode{box: box, offset: int32(start), count: int32(n)}
+		return node
+	}
+	inner := func(mid int) int32 {
+		b.build(bb, start, mid)
+		second := b.build(bb, mid, end)
+
+		b.nodes[node] = bvhNode{box: box, offset: second, axis: uint8(axis)}
+		return node
+	}
+
+	if n <= bvhLeafSize || (extent == 0 && n <= bvhMaxLeafSize) {
+		return leaf()
+	}
+	if extent == 0 {
+		// the centroids cannot be told apart: any half will do
+		return inner((start + end) / 2)
+	}
+
+	binOf := func(c Vec3) int {
+		return minInt(int(bvhBins*(c.GetAxis(axis)-lo)/extent), bvhBins-1)
+	}
+
+	var bins [bvhBins]bvhBin
+	for i := start; i < end; i++ {
+		bins[binOf(bb.centroids[i])].add(1, bb.boxes[i])
+	}
+
+	// costs[k] is the cost of splitting between the bins k and k+1
+	var costs [bvhBins - 1]float64
+	var left, right bvhBin
+	for k := 0; k < bvhBins-1; k++ {
+		if bins[k].count > 0 {
+			left.add(bins[k].count, bins[k].box)
+		}
+		if left.count > 0 {
+			costs[k] = float64(left.count) * left.box.area()
+		}
+	}
+	for k := bvhBins - 1; k > 0; k-- {
+		if bins[k].count > 0 {
+			right.add(bins[k].count, bins[k].box)
+		}
+		if right.count > 0 {
+			costs[k-1] += float64(right.count) * right.box.area()
+		}
+	}
+
+	split, area := 0, box.area()
+	for k := range costs {
+		if costs[k] < costs[split] {
+			split = k
+		}
+	}
+	cost := float64(n)
+	if area > 0 {
+		cost = bvhTraversalCost + costs[split]/area
+	}
+	if n <= bvhMaxLeafSize && float64(n) <= cost {
+		return leaf()
+	}
+
+	// moves the primitives of the bins [0, split] before the others
+	mid := start
+	for i := start; i < end; i++ {
+		if binOf(bb.centroids[i]) <= split {
+			bb.swap(i, mid)
+			mid++
+		}
+	}
+	if mid == start || mid == end {
+		mid = (start + end) / 2
+		bb.selectNth(start, end, mid, axis)
+	}
+
+	return inner(mid)
+}
+
+// hit returns the closest hit among the primitives of p
+func (b *primitiveBVH) hit(p primitives, r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	if len(b.nodes) == 0 {
+		return false, nil
+	}
+
+	origin := r.Origin.Vec3()
+	invDir := Vec3{X: 1 / r.Dir.X, Y: 1 / r.Dir.Y, Z: 1 / r.Dir.Z}
+	dirNeg := [3]bool{invDir.X < 0, invDir.Y < 0, invDir.Z < 0}
+
+	var res *HitRecord
+	stack := make([]int32, 0, 64)
+	for node := int32(0); ; {
+		n := &b.nodes[node]
+		if n.box.hitInv(origin, invDir, tMin, tMax) {
+			if n.count == 0 {
+				// visits first the child on the side the ray comes from
+				if dirNeg[n.axis] {
+					stack = append(stack, node+1)
+					node = n.offset
+				} else {
+					stack = append(stack, n.offset)
+					node++
+				}
+				continue
+			}
+
+			for _, i := range b.indices[n.offset : n.offset+n.count] {
+				if hit, hr := p.hitPrimitive(int(i), r, tMin, tMax); hit {
+					res = hr
+					tMax = hr.T
+				}
+			}
+		}
+
+		if len(stack) == 0 {
+			break
+		}
+		node = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+	}
+
+	return res != nil, res
+}
+
+// refit recomputes the boxes of the nodes bottom-up from the new boxes of the primitives (indexed like the boxes
+// given to newPrimitiveBVH), keeping the tree as it is. It returns false when the tree has become too expensive
+// to traverse with the new boxes (see bvhRebuildRatio) and should be rebuilt.
+func (b *primitiveBVH) refit(boxes []AABB) bool {
+	// the children of a node are stored after it
+	for i := len(b.nodes) - 1; i >= 0; i-- {
+		n := &b.nodes[i]
+		if n.count == 0 {
+			n.box = b.nodes[i+1].box.union(b.nodes[n.offset].box)
+			continue
+		}
+
+		box := boxes[b.indices[n.offset]]
+		for _, j := range b.indices[n.offset+1 : n.offset+n.count] {
+			box = box.union(boxes[j])
+		}
+		n.box = box
+	}
+
+	return b.sahCost() <= bvhRebuildRatio*b.cost
+}
+
+// sahCost returns the expected cost of a ray crossing the box of the hierarchy according to the surface area
+// heuristic (the probability to visit a node is the ratio of its area to the area of the root)
+func (b *primitiveBVH) sahCost() float64 {
+	if len(b.nodes) == 0 || b.nodes[0].box.area() == 0 {
+		return 0
+	}
+
+	cost := 0.0
+	for _, n := range b.nodes {
+		if n.count == 0 {
+			cost += bvhTraversalCost * n.box.area()
+		} else {
+			cost += float64(n.count) * n.box.area()
+		}
+	}
+
+	return cost / b.nodes[0].box.area()
+}
+
+// selectNth reorders the primitives [start, end[ so that the n-th one is the one it would be if they were sorted
+// by centroid along the axis, with the smaller ones before it and the larger ones after it (quickselect: linear
+// time on average, unlike a sort)
+func (bb *bvhBuilder) selectNth(start, end, n int, axis int) {
+	centroids := bb.centroids
+	lo, hi := start, end-1
+	for lo < hi {
+		// median of 3 pivot, which handles sorted inputs well
+		m := (lo + hi) / 2
+		if centroids[m].GetAxis(axis) < centroids[lo].GetAxis(axis) {
+			bb.swap(m, lo)
+		}
+		if centroids[hi].GetAxis(axis) < centroids[lo].GetAxis(axis) {
+			bb.swap(hi, lo)
+		}
+		if centroids[hi].GetAxis(axis) < centroids[m].GetAxis(axis) {
+			bb.swap(hi, m)
+		}
+		pivot := centroids[m].GetAxis(axis)
+
+		i, j := lo, hi
+		for i <= j {
+			for centroids[i].GetAxis(axis) < pivot {
+				i++
+			}
+			for centroids[j].GetAxis(axis) > pivot {
+				j--
+			}
+			if i <= j {
+				bb.swap(i, j)
+				i++
+				j--
+			}
+		}
+
+		switch {
+		case n <= j:
+			hi = j
+		case n >= i:
+			lo = i
+		default:
+			return
+		}
+	}
+}
+
+// bounds returns the bounding box of the whole hierarchy
+func (b *primitiveBVH) bounds() (bool, AABB) {
+	if len(b.nodes) == 0 {
+		return false, AABB{}
+	}
+
+	return true, b.nodes[0].box
+}
+
+// longestAxis returns the axis (0: X, 1: Y, 2: Z) along which the box is the largest
+func longestAxis(box AABB) int {
+	d := box.Max.Sub(box.Min)
+	switch {
+	case d.X >= d.Y && d.X >= d.Z:
+		return 0
+	case d.Y >= d.Z:
+		return 1
+	}
+
+	return 2
+}
+
+// BVH is a bounding volume hierarchy over HitTables (built with the surface area heuristic) so that a ray is only
+// intersected with the HitTables whose bounding box it crosses instead of all of them. The HitTables without
+// bounding box (a Plane...) cannot be organized: they are kept aside and intersected with every ray.
+type BVH struct {
+	hits      []HitTable
+	unbounded HitTableList
+	bvh       primitiveBVH
+}
+
+// NewBVH builds the hierarchy over the HitTables (the HitTableLists are replaced by their content), bounded
+// over the time interval [tm0, tm1] which is usually the shutter interval of the camera
+func NewBVH(tm0, tm1 float64, hits ...HitTable) *BVH {
+	b := &BVH{}
+	var boxes []AABB
+
+	var add func(h HitTable)
+	add = func(h HitTable) {
+		switch l := h.(type) {
+		case HitTableList:
+			for _, h := range l.Hits {
+				add(h)
+			}
+		case *HitTableList:
+			for _, h := range l.Hits {
+				add(h)
+			}
+		default:
+			if ok, box := h.BoundingBox(tm0, tm1); ok {
+				b.hits = append(b.hits, h)
+				boxes = append(boxes, *box)
+			} else {
+				b.unbounded.Hits = append(b.unbounded.Hits, h)
+			}
+		}
+	}
+	for _, h := range hits {
+		add(h)
+	}
+
+	b.bvh = newPrimitiveBVH(boxes)
+	return b
+}
+
+func (b *BVH) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	hit, res := b.bvh.hit(b, r, tMin, tMax)
+	if hit {
+		tMax = res.T
+	}
+	if hit2, hr := b.unbounded.Hit(r, tMin, tMax); hit2 {
+		return true, hr
+	}
+
+	return hit, res
+}
+
+// BoundingBox returns the box of the whole hierarchy, if none of its HitTables is unbounded
+func (b *BVH) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	if len(b.unbounded.Hits) > 0 {
+		return false, nil
+	}
+
+	ok, box := b.bvh.bounds()
+	return ok, &box
+}
+
+// Refit updates the hierarchy after its HitTables moved (e.g. for the next frame of an animation, with the shutter
+// interval of that frame) without rebuilding it: the boxes of the nodes are recomputed bottom-up from the new
+// bounding boxes of the HitTables. The hierarchy is rebuilt instead when it got too expensive to traverse with the
+// new boxes, or when a HitTable lost its bounding box. Refit returns whether the hierarchy was rebuilt.
+// The HitTables which had no bounding box stay aside.
+func (b *BVH) Refit(tm0, tm1 float64) bool {
+	boxes := make([]AABB, len(b.hits))
+	for i, h := range b.hits {
+		ok, box := h.BoundingBox(tm0, tm1)
+		if !ok {
+			hits := append(append([]HitTable(nil), b.hits...), b.unbounded.Hits...)
+			*b = *NewBVH(tm0, tm1, hits...)
+			return true
+		}
+		boxes[i] = *box
+	}
+
+	if b.bvh.refit(boxes) {
+		return false
+	}
+
+	b.bvh = newPrimitiveBVH(boxes)
+	return true
+}
+
+// hitPrimitive intersects the ray with the i-th HitTable
+func (b *BVH) hitPrimitive(i int, r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return b.hits[i].Hit(r, tMin, tMax)
+}
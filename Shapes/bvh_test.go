@@ -0,0 +1,443 @@
+package shapes
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// randomBoxes returns n random boxes, some of them sharing the same centroid
+func randomBoxes(rnd *rand.Rand, n int) []AABB {
+	boxes := make([]AABB, n)
+	for i := range boxes {
+		c := Vec3{X: 20*rnd.Float64() - 10, Y: 5 * rnd.Float64(), Z: 20*rnd.Float64() - 10}
+		if i%7 == 3 {
+			c = boxes[i-1].centroid()
+		}
+		d := Vec3{X: rnd.Float64(), Y: 0.1 * rnd.Float64(), Z: 2 * rnd.Float64()}
+		boxes[i] = AABB{Min: c.Sub(d), Max: c.Add(d)}
+	}
+
+	return boxes
+}
+
+// contains tells whether the box a contains the box b
+func contains(a, b AABB) bool {
+	return a.Min.X <= b.Min.X && a.Min.Y <= b.Min.Y && a.Min.Z <= b.Min.Z &&
+		a.Max.X >= b.Max.X && a.Max.Y >= b.Max.Y && a.Max.Z >= b.Max.Z
+}
+
+// checkBVH checks that every primitive is in exactly one leaf and that every node contains what is below it
+func checkBVH(t *testing.T, b *primitiveBVH, boxes []AABB) {
+	t.Helper()
+
+	if len(boxes) == 0 {
+		if ok, _ := b.bounds(); ok || len(b.nodes) != 0 {
+			t.Fatalf("empty hierarchy has %d nodes", len(b.nodes))
+		}
+		return
+	}
+
+	seen := make([]int, len(boxes))
+	var walk func(node int32) AABB
+	walk = func(node int32) AABB {
+		n := b.nodes[node]
+		if n.count > 0 {
+			if n.count > bvhMaxLeafSize {
+				t.Errorf("leaf %d holds %d primitives", node, n.count)
+			}
+			for _, i := range b.indices[n.offset : n.offset+n.count] {
+				seen[i]++
+				if !contains(n.box, boxes[i]) {
+					t.Errorf("leaf %d box %v does not contain primitive %d box %v", node, n.box, i, boxes[i])
+				}
+			}
+			return n.box
+		}
+
+		left, right := walk(node+1), walk(n.offset)
+		if !contains(n.box, left) || !contains(n.box, right) {
+			t.Errorf("node %d box %v does not contain its children %v, %v", node, n.box, left, right)
+		}
+		return n.box
+	}
+	walk(0)
+
+	for i, n := range seen {
+		if n != 1 {
+			t.Errorf("primitive %d is in %d leaves", i, n)
+		}
+	}
+}
+
+func TestPrimitiveBVHLeaves(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 2, 3, bvhMaxLeafSize + 1, 100, 5000} {
+		boxes := randomBoxes(rnd, n)
+		b := newPrimitiveBVH(boxes)
+		checkBVH(t, &b, boxes)
+	}
+
+	// identical primitives cannot be sorted but are still split into small leaves
+	for _, n := range []int{bvhMaxLeafSize, 50, 10000} {
+		same := make([]AABB, n)
+		for i := range same {
+			same[i] = AABB{Max: Vec3{X: 1, Y: 1, Z: 1}}
+		}
+		b := newPrimitiveBVH(same)
+		checkBVH(t, &b, same)
+		if n == bvhMaxLeafSize && len(b.nodes) != 1 {
+			t.Errorf("%d nodes for %d identical primitives, want 1", len(b.nodes), n)
+		}
+	}
+
+	// primitives of different sizes around the same centroid
+	nested := make([]AABB, 100)
+	for i := range nested {
+		d := float64(i + 1)
+		nested[i] = AABB{Min: Vec3{X: -d, Y: -d, Z: -d}, Max: Vec3{X: d, Y: d, Z: d}}
+	}
+	b := newPrimitiveBVH(nested)
+	checkBVH(t, &b, nested)
+
+	// primitives spread along a line
+	line := make([]AABB, 1000)
+	for i := range line {
+		x := float64(i)
+		line[i] = AABB{Min: Vec3{X: x}, Max: Vec3{X: x + 0.5, Y: 1, Z: 1}}
+	}
+	b = newPrimitiveBVH(line)
+	checkBVH(t, &b, line)
+}
+
+// randomWorld returns random spheres and boxes above a plane, partly nested in lists
+func randomWorld(rnd *rand.Rand, n int) []HitTable {
+	var hits []HitTable
+	for i := 0; i < n; i++ {
+		c := Point3{X: 20*rnd.Float64() - 10, Y: 3 * rnd.Float64(), Z: 20*rnd.Float64() - 10}
+		switch i % 3 {
+		case 0:
+			hits = append(hits, NewBox(c, c.Translate(Vec3{X: rnd.Float64(), Y: rnd.Float64(), Z: rnd.Float64()}), nil))
+		case 1:
+			hits = append(hits, Sphere{Center: c, R: 0.5 * rnd.Float64()})
+		default:
+			hits = append(hits, RotateY(Sphere{Center: c, R: 0.3}, 30))
+		}
+	}
+
+	return append(hits, Plane{Normal: Vec3{Y: 1}}, HitTableList{Hits: hits[: n/2 : n/2]})
+}
+
+// randomRay returns a ray crossing the random world
+func randomRay(rnd *rand.Rand) Ray {
+	return Ray{
+		Origin: Point3{X: 30*rnd.Float64() - 15, Y: 10 * rnd.Float64(), Z: 30*rnd.Float64() - 15},
+		Dir:    Vec3{X: 2*rnd.Float64() - 1, Y: 2*rnd.Float64() - 1, Z: 2*rnd.Float64() - 1},
+	}
+}
+
+// sameHits checks that 2 HitTables give the same closest hit for random rays
+func sameHits(t *testing.T, got, want HitTable, rnd *rand.Rand, rays int) {
+	t.Helper()
+
+	for i := 0; i < rays; i++ {
+		r := randomRay(rnd)
+		hit, hr := got.Hit(&r, 0.001, math.Inf(1))
+		wantHit, wantHR := want.Hit(&r, 0.001, math.Inf(1))
+		if hit != wantHit || (hit && hr.T != wantHR.T) {
+			t.Fatalf("ray %+v: hit %v %+v, want %v %+v", r, hit, hr, wantHit, wantHR)
+		}
+	}
+}
+
+func TestBVHMatchesLinearScan(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	for _, n := range []int{1, 10, 1000} {
+		world := randomWorld(rnd, n)
+		sameHits(t, NewBVH(0, 1, world...), HitTableList{Hits: world[:len(world)-1]}, rnd, 5000)
+	}
+}
+
+func TestBVHUnorderedTraversal(t *testing.T) {
+	rnd := rand.New(rand.NewSource(4))
+	world := randomWorld(rnd, 1000)
+	bvh := NewBVH(0, 1, world[:len(world)-2]...)
+
+	for i := 0; i < 5000; i++ {
+		r := randomRay(rnd)
+		hit, hr := bvh.bvh.hit(bvh, &r, 0.001, math.Inf(1))
+		wantHit, wantHR := bvh.bvh.hitUnordered(bvh, &r, 0.001, math.Inf(1))
+		if hit != wantHit || (hit && hr.T != wantHR.T) {
+			t.Fatalf("ray %+v: hit %v %+v, unordered %v %+v", r, hit, hr, wantHit, wantHR)
+		}
+	}
+}
+
+func TestBVHUnbounded(t *testing.T) {
+	plane := Plane{Normal: Vec3{Y: 1}}
+	sphere := Sphere{Center: Point3{Y: 2}, R: 1}
+
+	b := NewBVH(0, 1, plane, sphere)
+	if ok, _ := b.BoundingBox(0, 1); ok {
+		t.Error("a hierarchy holding a plane has a bounding box")
+	}
+	if ok, box := NewBVH(0, 1, sphere).BoundingBox(0, 1); !ok || box.Max.Y != 3 {
+		t.Errorf("box = %v, %v, want the box of the sphere", ok, box)
+	}
+
+	r := Ray{Origin: Point3{Y: 5}, Dir: Vec3{Y: -1}}
+	if hit, hr := b.Hit(&r, 0.001, math.Inf(1)); !hit || hr.T != 2 {
+		t.Errorf("hit %v %+v, want the sphere at t=2", hit, hr)
+	}
+	r = Ray{Origin: Point3{X: 5, Y: 5}, Dir: Vec3{Y: -1}}
+	if hit, hr := b.Hit(&r, 0.001, math.Inf(1)); !hit || hr.T != 5 {
+		t.Errorf("hit %v %+v, want the plane at t=5", hit, hr)
+	}
+}
+
+func TestNewBVHNode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	world := randomWorld(rnd, 100)
+	hl := HitTableList{Hits: world}
+
+	sameHits(t, NewBVHNode(10, 60, 0, 1, &hl), HitTableList{Hits: world[10:60]}, rnd, 2000)
+}
+
+func TestNewAABB(t *testing.T) {
+	a := AABB{Min: Vec3{X: -1, Y: 0, Z: 2}, Max: Vec3{X: 1, Y: 1, Z: 3}}
+	b := AABB{Min: Vec3{X: 0, Y: -2, Z: 0}, Max: Vec3{X: 4, Y: 0.5, Z: 1}}
+	want := AABB{Min: Vec3{X: -1, Y: -2, Z: 0}, Max: Vec3{X: 4, Y: 1, Z: 3}}
+
+	if got := NewAABB(a, b); got != want {
+		t.Errorf("NewAABB = %v, want %v", got, want)
+	}
+}
+
+// hitUnordered is the traversal of primitiveBVH.hit visiting always the left child first, whatever the direction
+// of the ray: the reference of the traversal benchmarks
+func (b *primitiveBVH) hitUnordered(p primitives, r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	if len(b.nodes) == 0 {
+		return false, nil
+	}
+
+	origin := r.Origin.Vec3()
+	invDir := Vec3{X: 1 / r.Dir.X, Y: 1 / r.Dir.Y, Z: 1 / r.Dir.Z}
+
+	var res *HitRecord
+	stack := make([]int32, 0, 64)
+	for node := int32(0); ; {
+		n := &b.nodes[node]
+		if n.box.hitInv(origin, invDir, tMin, tMax) {
+			if n.count == 0 {
+				stack = append(stack, n.offset)
+				node++
+				continue
+			}
+
+			for _, i := range b.indices[n.offset : n.offset+n.count] {
+				if hit, hr := p.hitPrimitive(int(i), r, tMin, tMax); hit {
+					res = hr
+					tMax = hr.T
+				}
+			}
+		}
+
+		if len(stack) == 0 {
+			break
+		}
+		node = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+	}
+
+	return res != nil, res
+}
+
+// finalScene is the world of the final scene of the builder: a plane, 3 big spheres and small spheres on a grid
+func finalScene(rnd *rand.Rand) []HitTable {
+	world := []HitTable{Plane{Normal: Vec3{Y: 1}}}
+	for a := -11; a < 11; a++ {
+		for b := -11; b < 11; b++ {
+			center := Point3{X: float64(a) + 0.9*rnd.Float64(), Y: 0.2, Z: float64(b) + 0.9*rnd.Float64()}
+			if center.Sub(Point3{X: 4, Y: 0.2}).Length() > 0.9 {
+				world = append(world, Sphere{Center: center, R: 0.2})
+			}
+		}
+	}
+
+	return append(world,
+		Sphere{Center: Point3{Y: 1}, R: 1},
+		Sphere{Center: Point3{X: -4, Y: 1}, R: 1},
+		Sphere{Center: Point3{X: 4, Y: 1}, R: 1})
+}
+
+// particles returns the spheres of the particles scene of the builder (a spiral galaxy)
+func particles(rnd *rand.Rand, count int) ([]Point3, []float64) {
+	centers := make([]Point3, count)
+	radii := make([]float64, count)
+	for i := range centers {
+		arm := float64(rnd.Intn(3)) * 2 * math.Pi / 3
+		d := 4 * math.Sqrt(rnd.Float64())
+		a := arm + d*1.2 + 0.15*rnd.NormFloat64()
+		centers[i] = Point3{X: d * math.Cos(a), Y: 1.2 + 0.15*rnd.NormFloat64()*(1.2-d/4), Z: d * math.Sin(a)}
+		radii[i] = 0.004 + 0.006*rnd.Float64()
+	}
+
+	return centers, radii
+}
+
+// cameraRays returns rays leaving the camera of the builder scenes towards their center
+func cameraRays(rnd *rand.Rand, lookFrom, lookAt Point3, spread float64) []Ray {
+	rays := make([]Ray, 1024)
+	for i := range rays {
+		target := lookAt.Translate(Vec3{X: spread * (2*rnd.Float64() - 1), Y: spread * (rnd.Float64() - 0.5), Z: spread * (2*rnd.Float64() - 1)})
+		rays[i] = Ray{Origin: lookFrom, Dir: target.Sub(lookFrom)}
+	}
+
+	return rays
+}
+
+// benchmarkTraversal benchmarks the ordered and unordered traversals of the hierarchy against the linear scan of
+// the list
+func benchmarkTraversal(b *testing.B, bvh *primitiveBVH, p primitives, list HitTable, rays []Ray) {
+	run := func(name string, hit func(r *Ray) bool) {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				hit(&rays[i%len(rays)])
+			}
+		})
+	}
+
+	run("ordered", func(r *Ray) bool {
+		hit, _ := bvh.hit(p, r, 0.001, math.Inf(1))
+		return hit
+	})
+	run("unordered", func(r *Ray) bool {
+		hit, _ := bvh.hitUnordered(p, r, 0.001, math.Inf(1))
+		return hit
+	})
+	run("list", func(r *Ray) bool {
+		hit, _ := list.Hit(r, 0.001, math.Inf(1))
+		return hit
+	})
+}
+
+func BenchmarkBVHFinalScene(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	world := finalScene(rnd)
+	bvh := NewBVH(0, 0, world[1:]...)
+
+	benchmarkTraversal(b, &bvh.bvh, bvh, HitTableList{Hits: world[1:]}, cameraRays(rnd, Point3{X: 13, Y: 2, Z: 3}, Point3{}, 6))
+}
+
+func BenchmarkBVHParticles(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	centers, radii := particles(rnd, 100000)
+	ss := NewSphereSet(centers, radii, nil, nil)
+	list := HitTableList{Hits: make([]HitTable, len(centers))}
+	for i, c := range centers {
+		list.Hits[i] = Sphere{Center: c, R: radii[i]}
+	}
+
+	benchmarkTraversal(b, &ss.bvh, ss, list, cameraRays(rnd, Point3{X: 13, Y: 4, Z: 6}, Point3{Y: 0.5}, 4))
+}
+
+// movingSphere is a sphere which can be moved after the hierarchy is built
+type movingSphere struct {
+	*Sphere
+}
+
+// movingSpheres returns count random spheres which can be moved
+func movingSpheres(rnd *rand.Rand, count int) ([]HitTable, []*Sphere) {
+	hits := make([]HitTable, count)
+	spheres := make([]*Sphere, count)
+	for i := range hits {
+		spheres[i] = &Sphere{Center: Point3{X: 20*rnd.Float64() - 10, Y: 3 * rnd.Float64(), Z: 20*rnd.Float64() - 10}, R: 0.1 + 0.4*rnd.Float64()}
+		hits[i] = movingSphere{spheres[i]}
+	}
+
+	return hits, spheres
+}
+
+func TestBVHRefit(t *testing.T) {
+	rnd := rand.New(rand.NewSource(5))
+	hits, spheres := movingSpheres(rnd, 2000)
+	b := NewBVH(0, 1, hits...)
+
+	// small moves keep the tree
+	for _, s := range spheres {
+		s.Center = s.Center.Translate(Vec3{X: 0.1*rnd.Float64() - 0.05, Y: 0.1 * rnd.Float64(), Z: 0.1*rnd.Float64() - 0.05})
+	}
+	if b.Refit(0, 1) {
+		t.Error("small moves rebuilt the hierarchy")
+	}
+	sameHits(t, b, NewBVH(0, 1, hits...), rnd, 5000)
+	sameHits(t, b, HitTableList{Hits: hits}, rnd, 5000)
+
+	// scattering the spheres makes the refitted tree too expensive
+	for _, s := range spheres {
+		s.Center = Point3{X: 20*rnd.Float64() - 10, Y: 3 * rnd.Float64(), Z: 20*rnd.Float64() - 10}
+	}
+	if !b.Refit(0, 1) {
+		t.Error("scattering the spheres did not rebuild the hierarchy")
+	}
+	sameHits(t, b, HitTableList{Hits: hits}, rnd, 5000)
+}
+
+func TestPrimitiveBVHRefit(t *testing.T) {
+	rnd := rand.New(rand.NewSource(6))
+	boxes := randomBoxes(rnd, 1000)
+	b := newPrimitiveBVH(boxes)
+	fresh := newPrimitiveBVH(boxes)
+
+	// refitting with the same boxes gives the tree which was built
+	for i := range b.nodes {
+		b.nodes[i].box = AABB{}
+	}
+	if !b.refit(boxes) {
+		t.Error("refit with the same boxes asks for a rebuild")
+	}
+	for i := range b.nodes {
+		if b.nodes[i] != fresh.nodes[i] {
+			t.Fatalf("node %d = %+v, want %+v", i, b.nodes[i], fresh.nodes[i])
+		}
+	}
+
+	// a permutation of the boxes keeps the same primitives but puts far apart the ones of a same leaf
+	rnd.Shuffle(len(boxes), func(i, j int) { boxes[i], boxes[j] = boxes[j], boxes[i] })
+	if b.refit(boxes) {
+		t.Error("refit with scattered boxes does not ask for a rebuild")
+	}
+	checkBVH(t, &b, boxes)
+}
+
+func TestHitTableListBoundingBox(t *testing.T) {
+	list := HitTableList{Hits: []HitTable{
+		Sphere{Center: Point3{X: -1}, R: 1},
+		NewBox(Point3{}, Point3{X: 3, Y: 1, Z: 1}, nil),
+	}}
+	want := AABB{Min: Vec3{X: -2, Y: -1, Z: -1}, Max: Vec3{X: 3, Y: 1, Z: 1}}
+	if ok, box := list.BoundingBox(0, 1); !ok || *box != want {
+		t.Errorf("list box = %v, %v, want %v", ok, box, want)
+	}
+
+	want = AABB{Min: Vec3{X: -2, Y: 1, Z: -1}, Max: Vec3{X: 3, Y: 3, Z: 1}}
+	if ok, box := Translate(list, Vec3{Y: 2}).BoundingBox(0, 1); !ok || *box != want {
+		t.Errorf("transformed list box = %v, %v, want %v", ok, box, want)
+	}
+
+	if ok, _ := (HitTableList{}).BoundingBox(0, 1); ok {
+		t.Error("an empty list has a bounding box")
+	}
+	if ok, _ := (HitTableList{Hits: append(list.Hits, Plane{Normal: Vec3{Y: 1}})}).BoundingBox(0, 1); ok {
+		t.Error("a list holding a plane has a bounding box")
+	}
+
+	// the instances of a list are in the hierarchy
+	in, err := NewInstances(0, 1, []HitTable{list}, []Instance{{Matrix: IdentityMatrix()}, {Matrix: TranslationMatrix(Vec3{Z: 5})}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in.unbounded) != 0 {
+		t.Errorf("%d unbounded instances of a list, want 0", len(in.unbounded))
+	}
+}
@@ -0,0 +1,253 @@
+package shapes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// maxSurfaceCrossings limits the number of surfaces surfaceSpans goes through along a ray
+const maxSurfaceCrossings = 64
+
+// Span is the part of a ray which is inside a solid, from the Enter hit to the Exit hit. Both records have the
+// outward normal of the surface crossed.
+type Span struct {
+	Enter, Exit *HitRecord
+}
+
+// Solid is a closed HitTable which can tell which parts of a ray are inside it
+type Solid interface {
+	HitTable
+
+	// Spans returns the spans (ordered along the ray) of the whole line going through the ray, negative t
+	// included, so that it is known whether the origin of the ray is inside the solid
+	Spans(r *Ray) []Span
+}
+
+// surfaceSpans computes the spans of a closed HitTable by walking through all the surfaces it has along the
+// ray: the ray enters the solid when it goes against the normal and exits when it goes along it
+func surfaceSpans(h HitTable, r *Ray) []Span {
+	var (
+		spans []Span
+		enter *HitRecord
+	)
+
+	t := math.Inf(-1)
+	for i := 0; i < maxSurfaceCrossings; i++ {
+		hit, hr := h.Hit(r, t, math.Inf(1))
+		if !hit {
+			break
+		}
+
+		if DotProduct(r.Dir, hr.Normal) < 0 {
+			if enter == nil {
+				enter = hr
+			}
+		} else if enter != nil {
+			spans = append(spans, Span{Enter: enter, Exit: hr})
+			enter = nil
+		}
+
+		t = hr.T + 1e-9*math.Max(1, math.Abs(hr.T))
+	}
+
+	return spans
+}
+
+// firstSpanHit returns the closest span boundary in ]tMin, tMax[
+func firstSpanHit(spans []Span, tMin, tMax float64) (bool, *HitRecord) {
+	for _, s := range spans {
+		if s.Enter.T > tMin && s.Enter.T < tMax {
+			return true, s.Enter
+		}
+		if s.Exit.T > tMin && s.Exit.T < tMax {
+			return true, s.Exit
+		}
+		if s.Exit.T >= tMax {
+			break
+		}
+	}
+
+	return false, nil
+}
+
+// CSGOperation is a boolean operation combining 2 solids
+type CSGOperation int
+
+const (
+	// Union keeps what is inside any of the solids
+	Union CSGOperation = iota
+	// Intersection keeps what is inside both solids
+	Intersection
+	// Difference keeps what is inside the first solid but not inside the second one
+	Difference
+)
+
+// contains tells whether a point inside a (or not) and inside b (or not) is inside the result of the operation
+func (op CSGOperation) contains(a, b bool) bool {
+	switch op {
+	case Union:
+		return a || b
+	case Intersection:
+		return a && b
+	}
+
+	return a && !b
+}
+
+// CSG is the solid made by combining the solids Left and Right with a boolean operation (constructive solid
+// geometry). Each surface keeps the material of the solid it comes from, the surfaces of Right carved out of
+// Left by a Difference have their normals reversed.
+// CSG nodes are solids as well and can be combined further.
+type CSG struct {
+	Op          CSGOperation
+	Left, Right Solid
+}
+
+// NewCSG returns the solid combining a and b with the operation. It fails when the spans of an operand are not
+// meaningful (see checkSolid).
+func NewCSG(op CSGOperation, a, b Solid) (CSG, error) {
+	if err := checkSolid(a); err != nil {
+		return CSG{}, err
+	}
+	if err := checkSolid(b); err != nil {
+		return CSG{}, err
+	}
+
+	return CSG{Op: op, Left: a, Right: b}, nil
+}
+
+// NewUnion returns the solid made of a and b
+func NewUnion(a, b Solid) (CSG, error) {
+	return NewCSG(Union, a, b)
+}
+
+// NewIntersection returns the solid common to a and b
+func NewIntersection(a, b Solid) (CSG, error) {
+	return NewCSG(Intersection, a, b)
+}
+
+// NewDifference returns the solid a with b removed
+func NewDifference(a, b Solid) (CSG, error) {
+	return NewCSG(Difference, a, b)
+}
+
+// checkSolid returns an error when the spans of s would be wrong because it is not closed: open or swept
+// quadrics, meshes with holes, and transforms of HitTables which are not solids (e.g. a Motion)
+func checkSolid(s Solid) error {
+	switch s := s.(type) {
+	case Cylinder:
+		if !s.Capped || sweepAngle(s.Sweep) < 2*math.Pi {
+			return fmt.Errorf("csg: open cylinder")
+		}
+	case Cone:
+		if !s.Capped || sweepAngle(s.Sweep) < 2*math.Pi {
+			return fmt.Errorf("csg: open cone")
+		}
+	case Torus:
+		if sweepAngle(s.Sweep) < 2*math.Pi {
+			return fmt.Errorf("csg: open torus")
+		}
+	case *Mesh:
+		if !s.closed() {
+			return fmt.Errorf("csg: mesh with holes")
+		}
+	case Transform:
+		inner, ok := s.HitTable.(Solid)
+		if !ok {
+			return fmt.Errorf("csg: transform of %T which is not a solid", s.HitTable)
+		}
+		return checkSolid(inner)
+	case CSG:
+		if err := checkSolid(s.Left); err != nil {
+			return err
+		}
+		return checkSolid(s.Right)
+	}
+
+	return nil
+}
+
+func (c CSG) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return firstSpanHit(c.Spans(r), tMin, tMax)
+}
+
+func (c CSG) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	okLeft, left := c.Left.BoundingBox(tm0, tm1)
+	okRight, right := c.Right.BoundingBox(tm0, tm1)
+
+	switch c.Op {
+	case Union:
+		if !okLeft || !okRight {
+			return false, nil
+		}
+		box := left.union(*right)
+		return true, &box
+	case Intersection:
+		switch {
+		case okLeft && okRight:
+			box := AABB{
+				Min: Vec3{X: math.Max(left.Min.X, right.Min.X), Y: math.Max(left.Min.Y, right.Min.Y), Z: math.Max(left.Min.Z, right.Min.Z)},
+				Max: Vec3{X: math.Min(left.Max.X, right.Max.X), Y: math.Min(left.Max.Y, right.Max.Y), Z: math.Min(left.Max.Z, right.Max.Z)},
+			}
+			return true, &box
+		case okLeft:
+			return true, left
+		case okRight:
+			return true, right
+		}
+		return false, nil
+	}
+
+	return okLeft, left
+}
+
+// csgEvent is a span boundary of one of the operands
+type csgEvent struct {
+	rec   *HitRecord
+	enter bool
+	left  bool
+}
+
+func (c CSG) Spans(r *Ray) []Span {
+	var events []csgEvent
+	for _, s := range c.Left.Spans(r) {
+		events = append(events, csgEvent{rec: s.Enter, enter: true, left: true}, csgEvent{rec: s.Exit, left: true})
+	}
+	for _, s := range c.Right.Spans(r) {
+		events = append(events, csgEvent{rec: s.Enter, enter: true}, csgEvent{rec: s.Exit})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].rec.T < events[j].rec.T
+	})
+
+	var (
+		spans           []Span
+		enter           *HitRecord
+		inLeft, inRight bool
+	)
+	for _, e := range events {
+		if e.left {
+			inLeft = e.enter
+		} else {
+			inRight = e.enter
+		}
+
+		rec := e.rec
+		if c.Op == Difference && !e.left {
+			flipped := *rec
+			flipped.Normal = rec.Normal.Negate()
+			rec = &flipped
+		}
+
+		inside := c.Op.contains(inLeft, inRight)
+		if inside && enter == nil {
+			enter = rec
+		} else if !inside && enter != nil {
+			spans = append(spans, Span{Enter: enter, Exit: rec})
+			enter = nil
+		}
+	}
+
+	return spans
+}
@@ -0,0 +1,172 @@
+package shapes
+
+import (
+	"math"
+	"testing"
+)
+
+// spanTimes returns the [enter, exit] t of the spans
+func spanTimes(spans []Span) [][2]float64 {
+	var ts [][2]float64
+	for _, s := range spans {
+		ts = append(ts, [2]float64{s.Enter.T, s.Exit.T})
+	}
+
+	return ts
+}
+
+func sameSpans(got, want [][2]float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if math.Abs(got[i][0]-want[i][0]) > 1e-9 || math.Abs(got[i][1]-want[i][1]) > 1e-9 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestCSGSpans(t *testing.T) {
+	// along the ray, a covers t in [4, 6], b covers [5, 7] and far covers [9, 11]
+	a := Sphere{Center: Point3{}, R: 1}
+	b := Sphere{Center: Point3{X: 1}, R: 1}
+	far := Sphere{Center: Point3{X: 5}, R: 1}
+	r := Ray{Origin: Point3{X: -5}, Dir: Vec3{X: 1}}
+
+	tests := []struct {
+		name string
+		op   CSGOperation
+		a, b Solid
+		want [][2]float64
+	}{
+		{"union", Union, a, b, [][2]float64{{4, 7}}},
+		{"intersection", Intersection, a, b, [][2]float64{{5, 6}}},
+		{"difference", Difference, a, b, [][2]float64{{4, 5}}},
+		{"reversed difference", Difference, b, a, [][2]float64{{6, 7}}},
+		{"disjoint union", Union, a, far, [][2]float64{{4, 6}, {9, 11}}},
+		{"disjoint intersection", Intersection, a, far, nil},
+		{"disjoint difference", Difference, a, far, [][2]float64{{4, 6}}},
+		{"difference splitting a", Difference, Sphere{R: 3}, Sphere{R: 1}, [][2]float64{{2, 4}, {6, 8}}},
+		{"nested", Union, CSG{Op: Difference, Left: a, Right: b}, far, [][2]float64{{4, 5}, {9, 11}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCSG(tt.op, tt.a, tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spanTimes(c.Spans(&r)); !sameSpans(got, tt.want) {
+				t.Errorf("spans = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSGDifferenceNormals(t *testing.T) {
+	c, err := NewDifference(Sphere{R: 1}, Sphere{Center: Point3{X: 1}, R: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := Ray{Origin: Point3{X: -5}, Dir: Vec3{X: 1}}
+	spans := c.Spans(&r)
+	if len(spans) != 1 {
+		t.Fatalf("%d spans, want 1", len(spans))
+	}
+	// the exit is the surface of b carved out of a: its normal is reversed to point out of the result, towards +X
+	if n := spans[0].Exit.Normal; n.X <= 0 {
+		t.Errorf("exit normal = %v, want it towards +X", n)
+	}
+	if n := spans[0].Enter.Normal; n.X >= 0 {
+		t.Errorf("enter normal = %v, want it towards -X", n)
+	}
+}
+
+func TestCSGHit(t *testing.T) {
+	c, err := NewIntersection(Sphere{R: 1}, Sphere{Center: Point3{X: 1}, R: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		r     Ray
+		hit   bool
+		wantT float64
+	}{
+		{"from outside", Ray{Origin: Point3{X: -5}, Dir: Vec3{X: 1}}, true, 5},
+		{"from inside", Ray{Origin: Point3{X: 0.5}, Dir: Vec3{X: 1}}, true, 0.5},
+		{"away", Ray{Origin: Point3{X: -5}, Dir: Vec3{X: -1}}, false, 0},
+		{"through a only", Ray{Origin: Point3{X: -0.5, Y: -5}, Dir: Vec3{Y: 1}}, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hit, hr := c.Hit(&tt.r, 0.001, math.Inf(1))
+			if hit != tt.hit {
+				t.Fatalf("hit = %v, want %v", hit, tt.hit)
+			}
+			if hit && math.Abs(hr.T-tt.wantT) > 1e-9 {
+				t.Errorf("t = %v, want %v", hr.T, tt.wantT)
+			}
+		})
+	}
+}
+
+func TestNewCSGRejectsOpenSolids(t *testing.T) {
+	closedCube := unitCubeMesh()
+	openCube := NewMesh(closedCube.Positions, nil, nil, closedCube.Indices[:len(closedCube.Indices)-6], nil, nil)
+	motion := NewMotion(Sphere{R: 1}, Keyframe{Time: 0}, Keyframe{Time: 1, Translation: Vec3{X: 1}})
+
+	tests := []struct {
+		name  string
+		solid Solid
+		ok    bool
+	}{
+		{"sphere", Sphere{R: 1}, true},
+		{"box", NewBox(Point3{}, Point3{X: 1, Y: 1, Z: 1}, nil), true},
+		{"capped cylinder", Cylinder{R: 1, Height: 1, Capped: true}, true},
+		{"capped cylinder with a full sweep", Cylinder{R: 1, Height: 1, Capped: true, Sweep: 360}, true},
+		{"open cylinder", Cylinder{R: 1, Height: 1}, false},
+		{"swept cylinder", Cylinder{R: 1, Height: 1, Capped: true, Sweep: 90}, false},
+		{"capped cone", Cone{R: 1, Height: 1, Capped: true}, true},
+		{"open cone", Cone{R: 1, Height: 1}, false},
+		{"torus", Torus{R: 1, Tube: 0.2}, true},
+		{"swept torus", Torus{R: 1, Tube: 0.2, Sweep: 180}, false},
+		{"closed mesh", closedCube, true},
+		{"mesh with a hole", openCube, false},
+		{"transformed sphere", Translate(Sphere{R: 1}, Vec3{X: 1}), true},
+		{"transformed open cylinder", RotateX(Cylinder{R: 1, Height: 1}, 90), false},
+		{"transformed motion", Translate(motion, Vec3{X: 1}), false},
+		{"csg of an open cylinder", CSG{Op: Union, Left: Sphere{R: 1}, Right: Cylinder{R: 1, Height: 1}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, op := range []CSGOperation{Union, Intersection, Difference} {
+				_, errLeft := NewCSG(op, tt.solid, Sphere{R: 1})
+				_, errRight := NewCSG(op, Sphere{R: 1}, tt.solid)
+				if (errLeft == nil) != tt.ok || (errRight == nil) != tt.ok {
+					t.Errorf("operation %d: errors %v, %v, want ok: %v", op, errLeft, errRight, tt.ok)
+				}
+			}
+		})
+	}
+}
+
+// unitCubeMesh returns the closed mesh of the cube [0, 1]³
+func unitCubeMesh() *Mesh {
+	var positions []Point3
+	for i := 0; i < 8; i++ {
+		positions = append(positions, Point3{X: float64(i & 1), Y: float64(i >> 1 & 1), Z: float64(i >> 2 & 1)})
+	}
+	var indices []int32
+	for _, q := range [6][4]int32{{0, 2, 3, 1}, {4, 5, 7, 6}, {0, 1, 5, 4}, {2, 6, 7, 3}, {0, 4, 6, 2}, {1, 3, 7, 5}} {
+		indices = append(indices, q[0], q[1], q[2], q[0], q[2], q[3])
+	}
+
+	return NewMesh(positions, nil, nil, indices, nil, nil)
+}
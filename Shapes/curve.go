@@ -0,0 +1,260 @@
+package shapes
+
+import (
+	"math"
+)
+
+const (
+	// curveMaxDepth limits the number of times a curve is split in 2 while it is intersected
+	curveMaxDepth = 10
+	// curveSegments is the number of pieces each curve of a Curves is cut into, so that the boxes of the
+	// hierarchy fit the strands closely
+	curveSegments = 4
+)
+
+// Curve is a thin strand (hair, fur, grass blade, cable...) following the cubic Bézier curve defined by 4 control
+// points. Its width goes linearly from Width0 at the first point to Width1 at the last one.
+// The curve is a flat ribbon always facing the ray, with normals bent across its width so that it is shaded like
+// a tube. v goes along the curve from 0 to 1 and u across it from 0 to 1.
+type Curve struct {
+	Points         [4]Point3
+	Width0, Width1 float64
+	Material       Material
+}
+
+// width returns the width of the curve at u
+func (c *Curve) width(u float64) float64 {
+	return c.Width0 + u*(c.Width1-c.Width0)
+}
+
+func (c Curve) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return c.hitRange(r, 0, 1, tMin, tMax)
+}
+
+func (c Curve) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	box := c.box(0, 1)
+	return true, &box
+}
+
+// box returns the bounding box of the part of the curve between u0 and u1: the control points of a Bézier curve
+// contain it
+func (c *Curve) box(u0, u1 float64) AABB {
+	var world [4]Vec3
+	for i, p := range c.Points {
+		world[i] = p.Vec3()
+	}
+	cp := subBezier(world, u0, u1)
+
+	hw := math.Max(c.width(u0), c.width(u1)) / 2
+	box := AABB{Min: cp[0], Max: cp[0]}
+	for _, p := range cp[1:] {
+		box = box.union(AABB{Min: p, Max: p})
+	}
+	box.Min = box.Min.Sub(Vec3{X: hw, Y: hw, Z: hw})
+	box.Max = box.Max.Add(Vec3{X: hw, Y: hw, Z: hw})
+
+	return box
+}
+
+// hitRange intersects the ray with the part of the curve between u0 and u1.
+// The curve is moved into the space of the ray (the ray goes from the origin along Z), where it is hit when it
+// passes close enough to the origin. It is split recursively, the bounding boxes of the pieces discarding most of
+// it, until the pieces are almost straight.
+func (c *Curve) hitRange(r *Ray, u0, u1 float64, tMin, tMax float64) (bool, *HitRecord) {
+	l2 := DotProduct(r.Dir, r.Dir)
+	if l2 == 0 {
+		return false, nil
+	}
+
+	// X and Y are in world units and Z is the t of the ray
+	x, y := orthonormalBasis(r.Dir.Scale(1 / math.Sqrt(l2)))
+	cr := curveRay{curve: c, r: r, invLength: 1 / math.Sqrt(l2)}
+	for i, p := range c.Points {
+		d := p.Sub(r.Origin)
+		cr.world[i] = p.Vec3()
+		cr.cp[i] = Vec3{X: DotProduct(d, x), Y: DotProduct(d, y), Z: DotProduct(d, r.Dir) / l2}
+	}
+	sub := subBezier(cr.cp, u0, u1)
+
+	// the number of splits needed for the pieces to deviate from a line by less than 5% of the width
+	var l float64
+	for i := 0; i < 2; i++ {
+		l = math.Max(l, math.Max(math.Abs(sub[i].X-2*sub[i+1].X+sub[i+2].X), math.Abs(sub[i].Y-2*sub[i+1].Y+sub[i+2].Y)))
+	}
+	depth := 0
+	if eps := 0.05 * math.Max(c.Width0, c.Width1); eps > 0 && l > 0 {
+		depth = clampInt(int(math.Log2(math.Sqrt2*6*l/(8*eps))/2), 0, curveMaxDepth)
+	}
+
+	return cr.hit(sub, u0, u1, depth, tMin, tMax)
+}
+
+// curveRay is a curve moved into the space of a ray
+type curveRay struct {
+	curve     *Curve
+	r         *Ray
+	cp        [4]Vec3
+	world     [4]Vec3
+	invLength float64
+}
+
+// hit intersects the ray with the piece of curve between u0 and u1 (whose control points are cp)
+func (cr *curveRay) hit(cp [4]Vec3, u0, u1 float64, depth int, tMin, tMax float64) (bool, *HitRecord) {
+	hw := math.Max(cr.curve.width(u0), cr.curve.width(u1)) / 2
+	box := AABB{Min: cp[0], Max: cp[0]}
+	for _, p := range cp[1:] {
+		box = box.union(AABB{Min: p, Max: p})
+	}
+	if box.Min.X-hw > 0 || box.Max.X+hw < 0 || box.Min.Y-hw > 0 || box.Max.Y+hw < 0 ||
+		box.Min.Z-hw*cr.invLength >= tMax || box.Max.Z+hw*cr.invLength <= tMin {
+		return false, nil
+	}
+
+	if depth > 0 {
+		left, right := splitBezier(cp)
+		um := (u0 + u1) / 2
+		hitLeft, hrLeft := cr.hit(left, u0, um, depth-1, tMin, tMax)
+		if hitLeft {
+			tMax = hrLeft.T
+		}
+		if hitRight, hrRight := cr.hit(right, um, u1, depth-1, tMin, tMax); hitRight {
+			return true, hrRight
+		}
+		return hitLeft, hrLeft
+	}
+
+	// the origin must be between the lines orthogonal to the piece at both ends, otherwise it belongs to the
+	// piece next to it
+	if (cp[1].Y-cp[0].Y)*-cp[0].Y+cp[0].X*(cp[0].X-cp[1].X) < 0 ||
+		(cp[2].Y-cp[3].Y)*-cp[3].Y+cp[3].X*(cp[3].X-cp[2].X) < 0 {
+		return false, nil
+	}
+
+	// the piece is almost a line: the closest point to the origin is looked for on the line
+	seg := cp[3].Sub(cp[0])
+	w := 0.0
+	if d := seg.X*seg.X + seg.Y*seg.Y; d > 0 {
+		w = math.Max(0, math.Min(1, -(cp[0].X*seg.X+cp[0].Y*seg.Y)/d))
+	}
+	u := u0 + w*(u1-u0)
+
+	p, _ := bezierPoint(cr.cp, u)
+	hw = cr.curve.width(u) / 2
+	if p.X*p.X+p.Y*p.Y > hw*hw || p.Z <= tMin || p.Z >= tMax {
+		return false, nil
+	}
+
+	hr := HitRecord{T: p.Z, P: cr.r.PointAt(p.Z), Mat: cr.curve.Material, V: u}
+
+	// the normal turns from facing the ray (center of the strand) to its sides (edges)
+	center, tangent := bezierPoint(cr.world, u)
+	view := cr.r.Dir.Negate().Unit()
+	side := Cross(tangent, view)
+	if side.Length() == 0 {
+		hr.Normal = view
+		hr.U = 0.5
+		return true, &hr
+	}
+	side = side.Unit()
+	front := Cross(side, tangent).Unit()
+
+	h := 0.0
+	if hw > 0 {
+		h = math.Max(-1, math.Min(1, DotProduct(hr.P.Sub(Point3{X: center.X, Y: center.Y, Z: center.Z}), side)/hw))
+	}
+	hr.Normal = front.Scale(math.Sqrt(1 - h*h)).Add(side.Scale(h)).Unit()
+	hr.U = (h + 1) / 2
+
+	return true, &hr
+}
+
+// bezierPoint returns the point of the cubic Bézier curve at u and its derivative
+func bezierPoint(cp [4]Vec3, u float64) (Vec3, Vec3) {
+	b, d := bernstein(u)
+	var p, dp Vec3
+	for i := range cp {
+		p = p.Add(cp[i].Scale(b[i]))
+		dp = dp.Add(cp[i].Scale(d[i]))
+	}
+
+	return p, dp
+}
+
+// splitBezier splits the cubic Bézier curve in 2 halves
+func splitBezier(cp [4]Vec3) ([4]Vec3, [4]Vec3) {
+	mid := func(a, b Vec3) Vec3 {
+		return a.Add(b).Scale(0.5)
+	}
+
+	p01, p12, p23 := mid(cp[0], cp[1]), mid(cp[1], cp[2]), mid(cp[2], cp[3])
+	p012, p123 := mid(p01, p12), mid(p12, p23)
+	p0123 := mid(p012, p123)
+
+	return [4]Vec3{cp[0], p01, p012, p0123}, [4]Vec3{p0123, p123, p23, cp[3]}
+}
+
+// subBezier returns the control points of the part of the cubic Bézier curve between u0 and u1, computed with
+// its blossom
+func subBezier(cp [4]Vec3, u0, u1 float64) [4]Vec3 {
+	if u0 == 0 && u1 == 1 {
+		return cp
+	}
+
+	lerp := func(t float64, a, b Vec3) Vec3 {
+		return a.Scale(1 - t).Add(b.Scale(t))
+	}
+	blossom := func(a, b, c float64) Vec3 {
+		p01, p12, p23 := lerp(a, cp[0], cp[1]), lerp(a, cp[1], cp[2]), lerp(a, cp[2], cp[3])
+		return lerp(c, lerp(b, p01, p12), lerp(b, p12, p23))
+	}
+
+	return [4]Vec3{blossom(u0, u0, u0), blossom(u0, u0, u1), blossom(u0, u1, u1), blossom(u1, u1, u1)}
+}
+
+// Curves is a large set of curves (e.g. the hair of a character) organized in a bounding volume hierarchy. Each
+// curve is cut into a few pieces in the hierarchy so that long bent strands do not get huge boxes.
+type Curves struct {
+	Curves []Curve
+
+	// the pieces of the curves: curve index and u range
+	pieces []curvePiece
+	bvh    primitiveBVH
+}
+
+// curvePiece is the part of a curve between u0 and u1
+type curvePiece struct {
+	curve  int32
+	u0, u1 float64
+}
+
+// NewCurves creates the set of curves and builds its acceleration structure
+func NewCurves(curves []Curve) *Curves {
+	cs := &Curves{Curves: curves, pieces: make([]curvePiece, 0, len(curves)*curveSegments)}
+
+	boxes := make([]AABB, 0, len(curves)*curveSegments)
+	for i := range curves {
+		for s := 0; s < curveSegments; s++ {
+			p := curvePiece{curve: int32(i), u0: float64(s) / curveSegments, u1: float64(s+1) / curveSegments}
+			cs.pieces = append(cs.pieces, p)
+			boxes = append(boxes, curves[i].box(p.u0, p.u1))
+		}
+	}
+	cs.bvh = newPrimitiveBVH(boxes)
+
+	return cs
+}
+
+func (cs *Curves) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return cs.bvh.hit(cs, r, tMin, tMax)
+}
+
+func (cs *Curves) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	ok, box := cs.bvh.bounds()
+	return ok, &box
+}
+
+// hitPrimitive intersects the ray with the i-th piece of curve
+func (cs *Curves) hitPrimitive(i int, r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	p := cs.pieces[i]
+	return cs.Curves[p.curve].hitRange(r, p.u0, p.u1, tMin, tMax)
+}
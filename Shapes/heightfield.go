@@ -0,0 +1,240 @@
+package shapes
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+)
+
+// Heightfield is a terrain defined by a regular grid of NX x NZ heights. It covers the horizontal rectangle
+// starting at Origin of size Size.X x Size.Z, and a height h (in [0, 1]) is at Origin.Y + h * Size.Y.
+// Every cell of the grid is made of 2 triangles with smooth normals. Rays are intersected by walking through
+// the cells they cross (no triangle is stored). u and v go from 0 to 1 along X and Z over the whole terrain.
+type Heightfield struct {
+	Heights  []float64
+	NX, NZ   int
+	Origin   Point3
+	Size     Vec3
+	Material Material
+
+	normals []Vec3
+	box     AABB
+}
+
+// NewHeightfield returns the terrain made of the heights (in [0, 1], row by row: heights[x + z*nx]). nx and nz
+// must be at least 2.
+func NewHeightfield(heights []float64, nx, nz int, origin Point3, size Vec3, m Material) *Heightfield {
+	hf := &Heightfield{Heights: heights, NX: nx, NZ: nz, Origin: origin, Size: size, Material: m}
+
+	minH, maxH := math.Inf(1), math.Inf(-1)
+	for _, h := range heights {
+		minH = math.Min(minH, h)
+		maxH = math.Max(maxH, h)
+	}
+	hf.box = AABB{
+		Min: Vec3{X: origin.X, Y: origin.Y + minH*size.Y, Z: origin.Z},
+		Max: Vec3{X: origin.X + size.X, Y: origin.Y + maxH*size.Y, Z: origin.Z + size.Z},
+	}.padded()
+
+	// the normals come from the slopes computed by central differences
+	dx, dz := hf.cellSize()
+	hf.normals = make([]Vec3, nx*nz)
+	for z := 0; z < nz; z++ {
+		for x := 0; x < nx; x++ {
+			x0, x1 := maxInt(x-1, 0), minInt(x+1, nx-1)
+			z0, z1 := maxInt(z-1, 0), minInt(z+1, nz-1)
+			sx := (hf.height(x1, z) - hf.height(x0, z)) / (float64(x1-x0) * dx)
+			sz := (hf.height(x, z1) - hf.height(x, z0)) / (float64(z1-z0) * dz)
+			hf.normals[x+z*nx] = Vec3{X: -sx, Y: 1, Z: -sz}.Unit()
+		}
+	}
+
+	return hf
+}
+
+// LoadHeights reads the grayscale image (8 or 16 bits per pixel) at path and returns its heights in [0, 1] (see
+// NewHeightfield) along with the size of the grid
+func LoadHeights(path string) ([]float64, int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("%v: %v", path, err)
+	}
+
+	b := img.Bounds()
+	heights := make([]float64, b.Dx()*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			// the conversion to 16 bits keeps all the precision of both formats
+			g := color.Gray16Model.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray16)
+			heights[x+y*b.Dx()] = float64(g.Y) / 0xFFFF
+		}
+	}
+
+	return heights, b.Dx(), b.Dy(), nil
+}
+
+// PerlinHeights returns a grid of nx x nz heights in [0, 1] made by summing octaves of the Perlin noise (each
+// one with twice the frequency and half the amplitude of the previous one). frequency is the number of noise
+// periods across the grid for the first octave.
+func PerlinHeights(p Perlin, nx, nz int, frequency float64, octaves int) []float64 {
+	heights := make([]float64, nx*nz)
+	minH, maxH := math.Inf(1), math.Inf(-1)
+	for z := 0; z < nz; z++ {
+		for x := 0; x < nx; x++ {
+			h, amplitude, f := 0.0, 1.0, frequency
+			for o := 0; o < octaves; o++ {
+				h += amplitude * p.noise(Point3{X: f * float64(x) / float64(nx), Z: f * float64(z) / float64(nz)})
+				amplitude /= 2
+				f *= 2
+			}
+
+			heights[x+z*nx] = h
+			minH = math.Min(minH, h)
+			maxH = math.Max(maxH, h)
+		}
+	}
+
+	if maxH > minH {
+		for i := range heights {
+			heights[i] = (heights[i] - minH) / (maxH - minH)
+		}
+	}
+
+	return heights
+}
+
+// cellSize returns the size of a cell of the grid along X and Z
+func (hf *Heightfield) cellSize() (float64, float64) {
+	return hf.Size.X / float64(hf.NX-1), hf.Size.Z / float64(hf.NZ-1)
+}
+
+// height returns the height (in world units, relative to Origin) of the grid point (x, z)
+func (hf *Heightfield) height(x, z int) float64 {
+	return hf.Heights[x+z*hf.NX] * hf.Size.Y
+}
+
+// point returns the grid point (x, z) in world space
+func (hf *Heightfield) point(x, z int) Point3 {
+	dx, dz := hf.cellSize()
+	return Point3{X: hf.Origin.X + float64(x)*dx, Y: hf.Origin.Y + hf.height(x, z), Z: hf.Origin.Z + float64(z)*dz}
+}
+
+func (hf *Heightfield) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	if hf.NX < 2 || hf.NZ < 2 {
+		return false, nil
+	}
+
+	hit, t0, t1 := hf.box.clip(r, tMin, tMax)
+	if !hit {
+		return false, nil
+	}
+
+	// 2D traversal of the cells crossed by the ray (from its entry into the box)
+	dx, dz := hf.cellSize()
+	p := r.PointAt(t0)
+	x := clampInt(int(math.Floor((p.X-hf.Origin.X)/dx)), 0, hf.NX-2)
+	z := clampInt(int(math.Floor((p.Z-hf.Origin.Z)/dz)), 0, hf.NZ-2)
+
+	stepX, nextX, deltaX := gridStep(r.Origin.X, r.Dir.X, hf.Origin.X, dx, x)
+	stepZ, nextZ, deltaZ := gridStep(r.Origin.Z, r.Dir.Z, hf.Origin.Z, dz, z)
+
+	for {
+		if hit, hr := hf.hitCell(r, x, z, tMin, tMax); hit {
+			return true, hr
+		}
+
+		if nextX < nextZ {
+			if nextX > t1 {
+				return false, nil
+			}
+			x += stepX
+			nextX += deltaX
+		} else {
+			if nextZ > t1 {
+				return false, nil
+			}
+			z += stepZ
+			nextZ += deltaZ
+		}
+
+		if x < 0 || x > hf.NX-2 || z < 0 || z > hf.NZ-2 {
+			return false, nil
+		}
+	}
+}
+
+// gridStep returns the direction of the traversal along an axis of the grid, the t of the first cell boundary
+// crossed and the t between 2 boundaries
+func gridStep(origin, dir, gridOrigin, cellSize float64, cell int) (int, float64, float64) {
+	switch {
+	case dir > 0:
+		return 1, (gridOrigin + float64(cell+1)*cellSize - origin) / dir, cellSize / dir
+	case dir < 0:
+		return -1, (gridOrigin + float64(cell)*cellSize - origin) / dir, -cellSize / dir
+	}
+
+	return 0, math.Inf(1), math.Inf(1)
+}
+
+// hitCell intersects the ray with the 2 triangles of the cell (x, z)
+func (hf *Heightfield) hitCell(r *Ray, x, z int, tMin, tMax float64) (bool, *HitRecord) {
+	corners := [4][2]int{{x, z}, {x + 1, z}, {x + 1, z + 1}, {x, z + 1}}
+	var res *HitRecord
+
+	for _, tri := range [2][3]int{{0, 1, 2}, {0, 2, 3}} {
+		a, b, c := corners[tri[0]], corners[tri[1]], corners[tri[2]]
+		hit, t, u, v := intersectTriangle(r, hf.point(a[0], a[1]), hf.point(b[0], b[1]), hf.point(c[0], c[1]), tMin, tMax)
+		if !hit {
+			continue
+		}
+
+		w := 1 - u - v
+		p := r.PointAt(t)
+		n := hf.normals[a[0]+a[1]*hf.NX].Scale(w).
+			Add(hf.normals[b[0]+b[1]*hf.NX].Scale(u)).
+			Add(hf.normals[c[0]+c[1]*hf.NX].Scale(v)).Unit()
+
+		res = &HitRecord{
+			T:      t,
+			P:      p,
+			Normal: n,
+			Mat:    hf.Material,
+			U:      (p.X - hf.Origin.X) / hf.Size.X,
+			V:      (p.Z - hf.Origin.Z) / hf.Size.Z,
+		}
+		tMax = t
+	}
+
+	return res != nil, res
+}
+
+func (hf *Heightfield) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	box := hf.box
+	return true, &box
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func clampInt(v, min, max int) int {
+	return minInt(maxInt(v, min), max)
+}
@@ -0,0 +1,149 @@
+package shapes
+
+import "fmt"
+
+// Instance places a copy of the prototype Prototype of an Instances in the world with the transformation Matrix
+type Instance struct {
+	Prototype int
+	Matrix    Matrix
+}
+
+// Instances is a two-level acceleration structure for scenes where the same geometry is repeated many times:
+// every instance references one of the shared Prototypes (the bottom level, e.g. a Mesh which has its own
+// bounding volume hierarchy) and a bounding volume hierarchy over the instances (the top level) finds the ones a
+// ray may hit. The memory used grows with the prototypes rather than with the number of instances.
+// The instances can be moved (e.g. for every frame of an animation) with SetMatrix then Update, which only
+// refits the top level.
+type Instances struct {
+	Prototypes []HitTable
+
+	prototypes []prototype
+	instances  []Transform
+	refs       []int32
+	unbounded  []int32
+	bvh        primitiveBVH
+}
+
+// prototype holds the box of a prototype in its own space, or in the space of the HitTable it transforms when the
+// prototype is a Transform, since the matrix of its instances is then combined with the one of the prototype (see
+// NewTransform)
+type prototype struct {
+	bounded bool
+	box     AABB
+}
+
+// NewInstances creates the instances of the prototypes and builds the top level hierarchy. The prototypes are
+// bounded over the time interval [tm0, tm1] (see NewBVH). An error is returned when an instance references a
+// prototype which does not exist.
+func NewInstances(tm0, tm1 float64, prototypes []HitTable, instances []Instance) (*Instances, error) {
+	in := &Instances{Prototypes: prototypes, prototypes: make([]prototype, len(prototypes))}
+	for i, p := range prototypes {
+		if t, ok := p.(Transform); ok {
+			p = t.HitTable
+		}
+		if ok, box := p.BoundingBox(tm0, tm1); ok {
+			in.prototypes[i] = prototype{bounded: true, box: *box}
+		}
+	}
+
+	in.instances = make([]Transform, len(instances))
+	in.refs = make([]int32, len(instances))
+	for i, inst := range instances {
+		if inst.Prototype < 0 || inst.Prototype >= len(prototypes) {
+			return nil, fmt.Errorf("instances: instance %d references the prototype %d out of %d", i, inst.Prototype, len(prototypes))
+		}
+		in.instances[i] = NewTransform(prototypes[inst.Prototype], inst.Matrix)
+		in.refs[i] = int32(inst.Prototype)
+	}
+	in.rebuild(in.boxes())
+
+	return in, nil
+}
+
+// Len returns the number of instances
+func (in *Instances) Len() int {
+	return len(in.instances)
+}
+
+// Matrix returns the transformation of the i-th instance
+func (in *Instances) Matrix(i int) Matrix {
+	return in.instances[i].Matrix()
+}
+
+// SetMatrix changes the transformation of the i-th instance. The change is taken into account by the next
+// Update, which must be called before rendering.
+func (in *Instances) SetMatrix(i int, m Matrix) {
+	in.instances[i] = NewTransform(in.Prototypes[in.refs[i]], m)
+}
+
+// Update refits the top level hierarchy to the current transformations of the instances (see BVH.Refit), or
+// rebuilds it when the instances moved too much. The prototypes are left as they are.
+func (in *Instances) Update() {
+	if boxes := in.boxes(); !in.bvh.refit(boxes) {
+		in.rebuild(boxes)
+	}
+}
+
+// boxes returns the bounding boxes of the instances (empty for the unbounded ones)
+func (in *Instances) boxes() []AABB {
+	boxes := make([]AABB, len(in.instances))
+	for i, t := range in.instances {
+		if p := in.prototypes[in.refs[i]]; p.bounded {
+			boxes[i] = t.matrix.Box(p.box)
+		}
+	}
+
+	return boxes
+}
+
+// rebuild builds the top level hierarchy over the bounded instances
+func (in *Instances) rebuild(boxes []AABB) {
+	bounded := make([]int32, 0, len(boxes))
+	bb := make([]AABB, 0, len(boxes))
+	in.unbounded = in.unbounded[:0]
+	for i := range in.instances {
+		if !in.prototypes[in.refs[i]].bounded {
+			in.unbounded = append(in.unbounded, int32(i))
+			continue
+		}
+		bounded = append(bounded, int32(i))
+		bb = append(bb, boxes[i])
+	}
+
+	in.bvh = newPrimitiveBVH(bb)
+	// the hierarchy indexes the bounded instances: maps them back to the instances, so that it can be refitted
+	// with the boxes of all the instances
+	for i, b := range in.bvh.indices {
+		in.bvh.indices[i] = bounded[b]
+	}
+}
+
+func (in *Instances) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	hit, res := in.bvh.hit(in, r, tMin, tMax)
+	if hit {
+		tMax = res.T
+	}
+
+	for _, i := range in.unbounded {
+		if h, hr := in.instances[i].Hit(r, tMin, tMax); h {
+			hit, res = true, hr
+			tMax = hr.T
+		}
+	}
+
+	return hit, res
+}
+
+func (in *Instances) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	if len(in.unbounded) > 0 {
+		return false, nil
+	}
+
+	ok, box := in.bvh.bounds()
+	return ok, &box
+}
+
+// hitPrimitive intersects the ray with the i-th instance
+func (in *Instances) hitPrimitive(i int, r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return in.instances[i].Hit(r, tMin, tMax)
+}
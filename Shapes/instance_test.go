@@ -0,0 +1,131 @@
+package shapes
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// instancePrototypes returns prototypes which are transformed or not, bounded or not
+func instancePrototypes() []HitTable {
+	return []HitTable{
+		Translate(NewBox(Point3{}, Point3{X: 1, Y: 1, Z: 1}, nil), Vec3{X: -0.5, Z: -0.5}),
+		Sphere{Center: Point3{Y: 0.5}, R: 0.5},
+		Translate(RotateY(NewBox(Point3{}, Point3{X: 2, Y: 0.5, Z: 1}, nil), 30), Vec3{X: 3, Y: 1}),
+	}
+}
+
+// randomInstances places count random instances of the prototypes
+func randomInstances(rnd *rand.Rand, prototypes, count int) []Instance {
+	instances := make([]Instance, count)
+	for i := range instances {
+		s := 0.2 + rnd.Float64()
+		k := Keyframe{
+			Translation: Vec3{X: 20*rnd.Float64() - 10, Y: 3 * rnd.Float64(), Z: 20*rnd.Float64() - 10},
+			Rotation:    Vec3{X: 90 * rnd.Float64(), Y: 360 * rnd.Float64()},
+			Scale:       Vec3{X: s, Y: s * (0.5 + rnd.Float64()), Z: s},
+		}
+		instances[i] = Instance{Prototype: rnd.Intn(prototypes), Matrix: k.Matrix()}
+	}
+
+	return instances
+}
+
+// bruteForce returns the list of the instances transformed one by one
+func bruteForce(prototypes []HitTable, instances []Instance) HitTableList {
+	var hl HitTableList
+	for _, inst := range instances {
+		hl.Hits = append(hl.Hits, NewTransform(prototypes[inst.Prototype], inst.Matrix))
+	}
+
+	return hl
+}
+
+func TestInstancesHit(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	prototypes := instancePrototypes()
+	for _, n := range []int{1, 10, 2000} {
+		instances := randomInstances(rnd, len(prototypes), n)
+		in, err := NewInstances(0, 1, prototypes, instances)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if in.Len() != n {
+			t.Errorf("Len = %d, want %d", in.Len(), n)
+		}
+
+		sameHits(t, in, bruteForce(prototypes, instances), rnd, 2000)
+	}
+}
+
+func TestInstancesTranslatedPrototypeBox(t *testing.T) {
+	// the box of the prototype is already translated: the translation must not be applied twice to the instance
+	prototypes := []HitTable{Translate(NewBox(Point3{}, Point3{X: 1, Y: 1, Z: 1}, nil), Vec3{X: 5})}
+	in, err := NewInstances(0, 1, prototypes, []Instance{{Matrix: TranslationMatrix(Vec3{Y: 2})}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := AABB{Min: Vec3{X: 5, Y: 2}, Max: Vec3{X: 6, Y: 3, Z: 1}}
+	if ok, box := in.BoundingBox(0, 1); !ok || *box != want {
+		t.Errorf("box = %v, %v, want %v", ok, box, want)
+	}
+}
+
+func TestInstancesUnbounded(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	prototypes := append(instancePrototypes(), Plane{Normal: Vec3{Y: 1}})
+	instances := randomInstances(rnd, len(prototypes), 100)
+	instances = append(instances, Instance{Prototype: len(prototypes) - 1, Matrix: TranslationMatrix(Vec3{Y: -1})})
+
+	in, err := NewInstances(0, 1, prototypes, instances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := in.BoundingBox(0, 1); ok {
+		t.Error("instances of a plane have a bounding box")
+	}
+
+	sameHits(t, in, bruteForce(prototypes, instances), rnd, 5000)
+}
+
+func TestNewInstancesBadPrototype(t *testing.T) {
+	prototypes := instancePrototypes()
+	for _, p := range []int{-1, len(prototypes)} {
+		if _, err := NewInstances(0, 1, prototypes, []Instance{{Matrix: IdentityMatrix()}, {Prototype: p, Matrix: IdentityMatrix()}}); err == nil {
+			t.Errorf("prototype %d: expected an error", p)
+		}
+	}
+}
+
+func TestInstancesUpdate(t *testing.T) {
+	rnd := rand.New(rand.NewSource(7))
+	prototypes := instancePrototypes()
+	instances := randomInstances(rnd, len(prototypes), 1000)
+	in, err := NewInstances(0, 1, prototypes, instances)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// small moves are refitted
+	cost := in.bvh.cost
+	for i := range instances {
+		instances[i].Matrix = TranslationMatrix(Vec3{X: 0.05, Y: 0.1 * rnd.Float64()}).Mult(instances[i].Matrix)
+		in.SetMatrix(i, instances[i].Matrix)
+	}
+	in.Update()
+	if in.bvh.cost != cost {
+		t.Error("small moves rebuilt the hierarchy")
+	}
+	sameHits(t, in, bruteForce(prototypes, instances), rnd, 2000)
+
+	// scattered instances are rebuilt
+	for i, inst := range randomInstances(rnd, len(prototypes), len(instances)) {
+		instances[i].Matrix = inst.Matrix
+		in.SetMatrix(i, inst.Matrix)
+	}
+	in.Update()
+	if in.bvh.cost == cost {
+		t.Error("scattering the instances did not rebuild the hierarchy")
+	}
+	sameHits(t, in, bruteForce(prototypes, instances), rnd, 2000)
+}
@@ -16,8 +16,8 @@ type Lambertian struct {
 
 func (l Lambertian) Scatter(r *Ray, rec *HitRecord) (bool, *Color, *Ray) {
 	target := rec.P.Translate(rec.Normal).Translate(RandomInUnitSphere(r.Rnd))
-	scattered := &Ray{Origin: rec.P, Dir: target.Sub(rec.P), Rnd: r.Rnd}
-	sc := l.Albedo.Value(rec.U, rec.V, rec.P)
+	scattered := &Ray{Origin: rec.P, Dir: target.Sub(rec.P), Rnd: r.Rnd, Time: r.Time}
+	sc := textureValue(l.Albedo, rec)
 	return true, &sc, scattered
 	
 }
@@ -34,7 +34,7 @@ func (m Metal) Scatter(r *Ray, rec *HitRecord) (bool, *Color, *Ray) {
 		reflected = reflected.Add(RandomInUnitSphere(r.Rnd).Scale(m.Fuzz))
 	}
 	
-	scattered := &Ray{Origin: rec.P, Dir: reflected, Rnd: r.Rnd}
+	scattered := &Ray{Origin: rec.P, Dir: reflected, Rnd: r.Rnd, Time: r.Time}
 	if DotProduct(scattered.Dir, rec.Normal) < 0 {
 		return false, nil, nil
 	}
@@ -75,9 +75,21 @@ func (d Dielectric) Scatter(r *Ray, rec *HitRecord) (bool, *Color, *Ray) {
 	wasRefracted, refracted := r.Dir.Refract(outwardNormal, niOverNt)
 	// refract only with some probability
 	if !wasRefracted || r.Rnd.Float64() < schlick(cosine, d.Ri) {
-		return true, &Color{R: 1.0, G: 1.0, B: 1.0}, &Ray{Origin: rec.P, Dir: r.Dir.Unit().Reflect(rec.Normal), Rnd: r.Rnd}
+		return true, &Color{R: 1.0, G: 1.0, B: 1.0}, &Ray{Origin: rec.P, Dir: r.Dir.Unit().Reflect(rec.Normal), Rnd: r.Rnd, Time: r.Time}
 	}
 	
-	return true, &Color{R: 1.0, G: 1.0, B: 1.0}, &Ray{Origin: rec.P, Dir: refracted, Rnd: r.Rnd}
+	return true, &Color{R: 1.0, G: 1.0, B: 1.0}, &Ray{Origin: rec.P, Dir: refracted, Rnd: r.Rnd, Time: r.Time}
 	
 }
+
+// Isotropic Material scatters the light uniformly in all directions (used as the phase function of the
+// participating media, see ConstantMedium)
+type Isotropic struct {
+	Albedo Texture
+}
+
+func (i Isotropic) Scatter(r *Ray, rec *HitRecord) (bool, *Color, *Ray) {
+	scattered := &Ray{Origin: rec.P, Dir: RandomInUnitSphere(r.Rnd), Rnd: r.Rnd, Time: r.Time}
+	attenuation := textureValue(i.Albedo, rec)
+	return true, &attenuation, scattered
+}
@@ -0,0 +1,159 @@
+package shapes
+
+import (
+	"math"
+)
+
+// Matrix is an affine transformation: a 3x3 linear part (the first 3 columns) followed by a translation (the
+// last column)
+type Matrix [3][4]float64
+
+// IdentityMatrix returns the transformation which does nothing
+func IdentityMatrix() Matrix {
+	return Matrix{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+	}
+}
+
+// TranslationMatrix returns the transformation moving the points by v
+func TranslationMatrix(v Vec3) Matrix {
+	return Matrix{
+		{1, 0, 0, v.X},
+		{0, 1, 0, v.Y},
+		{0, 0, 1, v.Z},
+	}
+}
+
+// ScaleMatrix returns the transformation scaling each axis by the matching component of v
+func ScaleMatrix(v Vec3) Matrix {
+	return Matrix{
+		{v.X, 0, 0, 0},
+		{0, v.Y, 0, 0},
+		{0, 0, v.Z, 0},
+	}
+}
+
+// RotationXMatrix returns the rotation of angle (in degrees) around the X axis
+func RotationXMatrix(angle float64) Matrix {
+	s, c := math.Sincos(angle * math.Pi / 180)
+	return Matrix{
+		{1, 0, 0, 0},
+		{0, c, -s, 0},
+		{0, s, c, 0},
+	}
+}
+
+// RotationYMatrix returns the rotation of angle (in degrees) around the Y axis
+func RotationYMatrix(angle float64) Matrix {
+	s, c := math.Sincos(angle * math.Pi / 180)
+	return Matrix{
+		{c, 0, s, 0},
+		{0, 1, 0, 0},
+		{-s, 0, c, 0},
+	}
+}
+
+// RotationZMatrix returns the rotation of angle (in degrees) around the Z axis
+func RotationZMatrix(angle float64) Matrix {
+	s, c := math.Sincos(angle * math.Pi / 180)
+	return Matrix{
+		{c, -s, 0, 0},
+		{s, c, 0, 0},
+		{0, 0, 1, 0},
+	}
+}
+
+// Mult returns the transformation applying n first and then m
+func (m Matrix) Mult(n Matrix) Matrix {
+	var res Matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 4; j++ {
+			v := m[i][0]*n[0][j] + m[i][1]*n[1][j] + m[i][2]*n[2][j]
+			if j == 3 {
+				v += m[i][3]
+			}
+			res[i][j] = v
+		}
+	}
+
+	return res
+}
+
+// Inverse returns the inverse transformation (false when the matrix cannot be inverted, e.g. a 0 scale)
+func (m Matrix) Inverse() (bool, Matrix) {
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	if det == 0 {
+		return false, Matrix{}
+	}
+
+	inv := 1 / det
+	var res Matrix
+	res[0][0] = (m[1][1]*m[2][2] - m[1][2]*m[2][1]) * inv
+	res[0][1] = (m[0][2]*m[2][1] - m[0][1]*m[2][2]) * inv
+	res[0][2] = (m[0][1]*m[1][2] - m[0][2]*m[1][1]) * inv
+	res[1][0] = (m[1][2]*m[2][0] - m[1][0]*m[2][2]) * inv
+	res[1][1] = (m[0][0]*m[2][2] - m[0][2]*m[2][0]) * inv
+	res[1][2] = (m[0][2]*m[1][0] - m[0][0]*m[1][2]) * inv
+	res[2][0] = (m[1][0]*m[2][1] - m[1][1]*m[2][0]) * inv
+	res[2][1] = (m[0][1]*m[2][0] - m[0][0]*m[2][1]) * inv
+	res[2][2] = (m[0][0]*m[1][1] - m[0][1]*m[1][0]) * inv
+
+	// the translation is the opposite of the original one, transformed by the inverted linear part
+	for i := 0; i < 3; i++ {
+		res[i][3] = -(res[i][0]*m[0][3] + res[i][1]*m[1][3] + res[i][2]*m[2][3])
+	}
+
+	return true, res
+}
+
+// Point transforms the point p
+func (m Matrix) Point(p Point3) Point3 {
+	return Point3{
+		X: m[0][0]*p.X + m[0][1]*p.Y + m[0][2]*p.Z + m[0][3],
+		Y: m[1][0]*p.X + m[1][1]*p.Y + m[1][2]*p.Z + m[1][3],
+		Z: m[2][0]*p.X + m[2][1]*p.Y + m[2][2]*p.Z + m[2][3],
+	}
+}
+
+// Vec3 transforms the vector v (the translation does not apply to vectors)
+func (m Matrix) Vec3(v Vec3) Vec3 {
+	return Vec3{
+		X: m[0][0]*v.X + m[0][1]*v.Y + m[0][2]*v.Z,
+		Y: m[1][0]*v.X + m[1][1]*v.Y + m[1][2]*v.Z,
+		Z: m[2][0]*v.X + m[2][1]*v.Y + m[2][2]*v.Z,
+	}
+}
+
+// TransposedVec3 transforms the vector v by the transposed linear part. Called on the inverse of a
+// transformation, it transforms the normals (which stay orthogonal to the transformed surface).
+func (m Matrix) TransposedVec3(v Vec3) Vec3 {
+	return Vec3{
+		X: m[0][0]*v.X + m[1][0]*v.Y + m[2][0]*v.Z,
+		Y: m[0][1]*v.X + m[1][1]*v.Y + m[2][1]*v.Z,
+		Z: m[0][2]*v.X + m[1][2]*v.Y + m[2][2]*v.Z,
+	}
+}
+
+// Box returns the axis-aligned box containing the transformed box
+func (m Matrix) Box(box AABB) AABB {
+	corners := make([]Point3, 0, 8)
+	for i := 0; i < 8; i++ {
+		c := box.Min
+		if i&1 != 0 {
+			c.X = box.Max.X
+		}
+		if i&2 != 0 {
+			c.Y = box.Max.Y
+		}
+		if i&4 != 0 {
+			c.Z = box.Max.Z
+		}
+		corners = append(corners, m.Point(Point3{X: c.X, Y: c.Y, Z: c.Z}))
+	}
+
+	return pointsAABB(corners...)
+}
@@ -0,0 +1,66 @@
+package shapes
+
+import (
+	"math"
+)
+
+// ConstantMedium is a volume of constant density (smoke, fog, mist...) filling a closed and convex boundary.
+// A ray going through the volume has a probability to scatter which increases with the distance travelled
+// inside it.
+type ConstantMedium struct {
+	Boundary      HitTable
+	PhaseFunction Material
+	negInvDensity float64
+}
+
+// NewConstantMedium returns the medium of the given density filling the boundary and scattering the light
+// isotropically with the albedo
+func NewConstantMedium(boundary HitTable, density float64, albedo Texture) ConstantMedium {
+	return ConstantMedium{
+		Boundary:      boundary,
+		PhaseFunction: Isotropic{Albedo: albedo},
+		negInvDensity: -1 / density,
+	}
+}
+
+func (cm ConstantMedium) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	// entry and exit points of the ray in the boundary (whole line, not only the [tMin, tMax] segment)
+	hit1, rec1 := cm.Boundary.Hit(r, math.Inf(-1), math.Inf(1))
+	if !hit1 {
+		return false, nil
+	}
+
+	hit2, rec2 := cm.Boundary.Hit(r, rec1.T+0.0001, math.Inf(1))
+	if !hit2 {
+		return false, nil
+	}
+
+	t1 := math.Max(rec1.T, tMin)
+	t2 := math.Min(rec2.T, tMax)
+	if t1 >= t2 {
+		return false, nil
+	}
+	t1 = math.Max(t1, 0)
+
+	rayLength := r.Dir.Length()
+	distanceInsideBoundary := (t2 - t1) * rayLength
+	hitDistance := cm.negInvDensity * math.Log(r.Rnd.Float64())
+	if hitDistance > distanceInsideBoundary {
+		return false, nil
+	}
+
+	t := t1 + hitDistance/rayLength
+	hr := HitRecord{
+		T: t,
+		P: r.PointAt(t),
+		// arbitrary: the isotropic phase function does not use it
+		Normal: Vec3{X: 1},
+		Mat:    cm.PhaseFunction,
+	}
+
+	return true, &hr
+}
+
+func (cm ConstantMedium) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	return cm.Boundary.BoundingBox(tm0, tm1)
+}
@@ -0,0 +1,235 @@
+package shapes
+
+import (
+	"math"
+)
+
+// DefaultMeshMaterial is used for the triangles of the meshes which do not define any material (the OBJ files use
+// DefaultOBJMaterial)
+var DefaultMeshMaterial Material = Lambertian{Albedo: NewSolidColor(Color{R: 0.7, G: 0.7, B: 0.7})}
+
+// Mesh is a triangle mesh storing its vertices once in shared buffers. Every triangle is defined by 3 indices
+// in the buffers, and the triangles are organized in a bounding volume hierarchy so that Hit does not have to
+// test them all.
+type Mesh struct {
+	Positions []Point3
+	Normals   []Vec3  // per vertex normals (optional, same indexing as Positions)
+	UVs       []UV    // per vertex texture coordinates (optional, same indexing as Positions)
+	Colors    []Color // per vertex colors (optional, same indexing as Positions, see VertexColorTexture)
+	Indices   []int32
+
+	// Materials holds the materials used by the mesh, MaterialIndices the index of the material of every
+	// triangle (optional, Materials[0] is used for all the triangles when empty). DefaultMeshMaterial is used
+	// when there is no material.
+	Materials       []Material
+	MaterialIndices []int32
+
+	bvh primitiveBVH
+}
+
+// NewMesh creates the mesh made of the triangles (indices[3i], indices[3i+1], indices[3i+2]) and builds its
+// acceleration structure. normals, uvs and materialIndices may be nil.
+func NewMesh(positions []Point3, normals []Vec3, uvs []UV, indices []int32, materials []Material, materialIndices []int32) *Mesh {
+	m := &Mesh{
+		Positions:       positions,
+		Normals:         normals,
+		UVs:             uvs,
+		Indices:         indices,
+		Materials:       materials,
+		MaterialIndices: materialIndices,
+	}
+
+	boxes := make([]AABB, m.TriangleCount())
+	for i := range boxes {
+		a, b, c := m.vertices(i)
+		boxes[i] = pointsAABB(m.Positions[a], m.Positions[b], m.Positions[c])
+	}
+	m.bvh = newPrimitiveBVH(boxes)
+
+	return m
+}
+
+// TriangleCount returns the number of triangles of the mesh
+func (m *Mesh) TriangleCount() int {
+	return len(m.Indices) / 3
+}
+
+// Triangle returns a standalone copy of the i-th triangle
+func (m *Mesh) Triangle(i int) Triangle {
+	a, b, c := m.vertices(i)
+	tr := Triangle{V0: m.Positions[a], V1: m.Positions[b], V2: m.Positions[c], Material: m.material(i)}
+	if len(m.Normals) > 0 {
+		tr.N0, tr.N1, tr.N2 = m.Normals[a], m.Normals[b], m.Normals[c]
+	}
+	if len(m.UVs) > 0 {
+		tr.UV0, tr.UV1, tr.UV2 = m.UVs[a], m.UVs[b], m.UVs[c]
+	}
+
+	return tr
+}
+
+// vertices returns the indices of the vertices of the i-th triangle
+func (m *Mesh) vertices(i int) (int32, int32, int32) {
+	return m.Indices[3*i], m.Indices[3*i+1], m.Indices[3*i+2]
+}
+
+// material returns the material of the i-th triangle
+func (m *Mesh) material(i int) Material {
+	if len(m.Materials) == 0 {
+		return DefaultMeshMaterial
+	}
+	if len(m.MaterialIndices) == 0 {
+		return m.Materials[0]
+	}
+
+	return m.Materials[m.MaterialIndices[i]]
+}
+
+func (m *Mesh) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return m.bvh.hit(m, r, tMin, tMax)
+}
+
+func (m *Mesh) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	ok, box := m.bvh.bounds()
+	return ok, &box
+}
+
+// hitPrimitive intersects the ray with the i-th triangle
+func (m *Mesh) hitPrimitive(i int, r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	a, b, c := m.vertices(i)
+	hit, t, u, v := intersectTriangle(r, m.Positions[a], m.Positions[b], m.Positions[c], tMin, tMax)
+	if !hit {
+		return false, nil
+	}
+
+	w := 1 - u - v
+	hr := HitRecord{
+		T:   t,
+		P:   r.PointAt(t),
+		Mat: m.material(i),
+		U:   u,
+		V:   v,
+	}
+
+	if len(m.Normals) > 0 {
+		hr.Normal = m.Normals[a].Scale(w).Add(m.Normals[b].Scale(u)).Add(m.Normals[c].Scale(v)).Unit()
+	} else {
+		hr.Normal = Cross(m.Positions[b].Sub(m.Positions[a]), m.Positions[c].Sub(m.Positions[a])).Unit()
+	}
+
+	if len(m.UVs) > 0 {
+		hr.U = w*m.UVs[a].U + u*m.UVs[b].U + v*m.UVs[c].U
+		hr.V = w*m.UVs[a].V + u*m.UVs[b].V + v*m.UVs[c].V
+	}
+
+	if len(m.Colors) > 0 {
+		col := m.Colors[a].Scale(w).Add(m.Colors[b].Scale(u)).Add(m.Colors[c].Scale(v))
+		hr.VertexColor = &col
+	}
+
+	return true, &hr
+}
+
+// smoothNormals computes the per vertex normals of the triangles: the normal of a triangle corner is the
+// (area weighted) average of the normals of the triangles sharing the vertex and making an angle lower than
+// angle (in degrees) with the triangle. The vertices whose corners end up with different normals are
+// duplicated, which is why new positions and indices are returned.
+func smoothNormals(positions []Point3, indices []int32, angle float64) ([]Point3, []Vec3, []int32) {
+	type corner struct {
+		position int32
+		normal   Vec3
+	}
+
+	count := len(indices) / 3
+	faceNormals := make([]Vec3, count)
+	faces := make([][]int32, len(positions))
+	for i := 0; i < count; i++ {
+		a, b, c := indices[3*i], indices[3*i+1], indices[3*i+2]
+		// not normalized: its length is twice the area of the triangle
+		faceNormals[i] = Cross(positions[b].Sub(positions[a]), positions[c].Sub(positions[a]))
+		faces[a] = append(faces[a], int32(i))
+		faces[b] = append(faces[b], int32(i))
+		faces[c] = append(faces[c], int32(i))
+	}
+
+	cosAngle := math.Cos(angle * math.Pi / 180)
+	vertices := map[corner]int32{}
+	var (
+		newPositions []Point3
+		normals      []Vec3
+	)
+
+	newIndices := make([]int32, len(indices))
+	for i := 0; i < count; i++ {
+		fn := faceNormals[i]
+		fl := fn.Length()
+		for k := 0; k < 3; k++ {
+			p := indices[3*i+k]
+
+			n := Vec3{}
+			for _, f := range faces[p] {
+				other := faceNormals[f]
+				if f == int32(i) || (fl > 0 && DotProduct(fn, other) >= cosAngle*fl*other.Length()) {
+					n = n.Add(other)
+				}
+			}
+			if l := n.Length(); l > 0 {
+				n = n.Scale(1 / l)
+			}
+
+			key := corner{position: p, normal: n}
+			v, ok := vertices[key]
+			if !ok {
+				v = int32(len(newPositions))
+				vertices[key] = v
+				newPositions = append(newPositions, positions[p])
+				normals = append(normals, n)
+			}
+			newIndices[3*i+k] = v
+		}
+	}
+
+	return newPositions, normals, newIndices
+}
+
+// Spans is meaningful for a closed mesh only (see NewCSG)
+func (m *Mesh) Spans(r *Ray) []Span {
+	return surfaceSpans(m, r)
+}
+
+// closed tells whether every edge of the mesh is shared by exactly 2 triangles. The vertices are compared by
+// position since they are often split along the sharp edges.
+func (m *Mesh) closed() bool {
+	type edge struct {
+		a, b Point3
+	}
+	less := func(p, q Point3) bool {
+		if p.X != q.X {
+			return p.X < q.X
+		}
+		if p.Y != q.Y {
+			return p.Y < q.Y
+		}
+		return p.Z < q.Z
+	}
+
+	edges := map[edge]int{}
+	for i := 0; i < m.TriangleCount(); i++ {
+		a, b, c := m.vertices(i)
+		for _, e := range [3][2]int32{{a, b}, {b, c}, {c, a}} {
+			p, q := m.Positions[e[0]], m.Positions[e[1]]
+			if less(q, p) {
+				p, q = q, p
+			}
+			edges[edge{a: p, b: q}]++
+		}
+	}
+
+	for _, n := range edges {
+		if n != 2 {
+			return false
+		}
+	}
+
+	return len(edges) > 0
+}
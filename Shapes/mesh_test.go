@@ -0,0 +1,21 @@
+package shapes
+
+import (
+	"testing"
+)
+
+func TestMeshWithoutMaterial(t *testing.T) {
+	m := NewMesh([]Point3{{}, {X: 1}, {Y: 1}}, nil, nil, []int32{0, 1, 2}, nil, nil)
+
+	r := Ray{Origin: Point3{X: 0.25, Y: 0.25, Z: 1}, Dir: Vec3{Z: -1}}
+	hit, hr := m.Hit(&r, 0.001, 10)
+	if !hit {
+		t.Fatal("expected a hit")
+	}
+	if hr.Mat != DefaultMeshMaterial {
+		t.Errorf("material = %#v, want DefaultMeshMaterial", hr.Mat)
+	}
+	if tr := m.Triangle(0); tr.Material != DefaultMeshMaterial {
+		t.Errorf("triangle material = %#v, want DefaultMeshMaterial", tr.Material)
+	}
+}
@@ -0,0 +1,190 @@
+package shapes
+
+import (
+	"math"
+)
+
+const (
+	// metaballEpsilon is the size (in world units) of the intervals of the ray under which they are not split
+	// anymore while looking for the surface
+	metaballEpsilon = 1e-4
+	// metaballRefinements is the number of bisections refining the position of a root of the field
+	metaballRefinements = 40
+)
+
+// Metaball is a center of the field of a Metaballs: it adds Weight * (1 - d^2/R^2)^3 to the field at distance d
+// (d < R) from Center. A negative weight carves the other balls.
+type Metaball struct {
+	Center Point3
+	R      float64
+	Weight float64
+}
+
+// Metaballs is the isosurface where the sum of the fields of the balls equals Threshold (lower than the weights
+// for the balls to be visible). Close balls merge smoothly into a single surface.
+// The surface is found by splitting the ray into intervals, dropping those where bounds of the field show that it
+// cannot reach the threshold, until the root is isolated. The normal is the gradient of the field.
+type Metaballs struct {
+	Balls     []Metaball
+	Threshold float64
+	Material  Material
+}
+
+// metaballRay is the squared distance to the center of a ball (relative to its radius) along the ray:
+// a*t^2 + b*t + c
+type metaballRay struct {
+	a, b, c float64
+	weight  float64
+}
+
+// s returns the squared distance relative to the radius at t
+func (mr *metaballRay) s(t float64) float64 {
+	return (mr.a*t+mr.b)*t + mr.c
+}
+
+// kernel returns the contribution of a ball at the relative squared distance s
+func kernel(s float64) float64 {
+	if s >= 1 {
+		return 0
+	}
+
+	k := 1 - s
+	return k * k * k
+}
+
+func (mb Metaballs) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	// only the balls the ray goes through contribute, and the surface is inside the balls adding to the field
+	var balls []metaballRay
+	start, end := math.Inf(1), math.Inf(-1)
+	for _, ball := range mb.Balls {
+		oc := r.Origin.Sub(ball.Center)
+		r2 := ball.R * ball.R
+		mr := metaballRay{
+			a:      DotProduct(r.Dir, r.Dir) / r2,
+			b:      2 * DotProduct(oc, r.Dir) / r2,
+			c:      DotProduct(oc, oc) / r2,
+			weight: ball.Weight,
+		}
+
+		roots := solveQuadratic(mr.a, mr.b, mr.c-1)
+		if len(roots) < 2 || roots[1] <= tMin || roots[0] >= tMax {
+			continue
+		}
+		balls = append(balls, mr)
+
+		if ball.Weight > 0 {
+			start = math.Min(start, math.Max(roots[0], tMin))
+			end = math.Max(end, math.Min(roots[1], tMax))
+		}
+	}
+	if start >= end {
+		return false, nil
+	}
+
+	field := func(t float64) float64 {
+		f := -mb.Threshold
+		for i := range balls {
+			f += balls[i].weight * kernel(balls[i].s(t))
+		}
+		return f
+	}
+
+	minLength := metaballEpsilon / r.Dir.Length()
+	t, ok := mb.root(balls, field, start, end, field(start), field(end), minLength)
+	if !ok {
+		return false, nil
+	}
+
+	p := r.PointAt(t)
+	hr := HitRecord{T: t, P: p, Normal: mb.normal(p), Mat: mb.Material}
+	hr.U, hr.V = UVCoordinates(hr.Normal)
+	return true, &hr
+}
+
+// root returns the first t in [t0, t1] where the field reaches the threshold (the field minus the threshold is f0
+// at t0 and f1 at t1)
+func (mb Metaballs) root(balls []metaballRay, field func(float64) float64, t0, t1, f0, f1, minLength float64) (float64, bool) {
+	// bounds of the field over the interval: each contribution is bound by the closest and farthest points of the
+	// interval to the center of the ball
+	low, high := -mb.Threshold, -mb.Threshold
+	for i := range balls {
+		b := &balls[i]
+		sMin, sMax := b.s(t0), b.s(t1)
+		if sMin > sMax {
+			sMin, sMax = sMax, sMin
+		}
+		if tc := -b.b / (2 * b.a); tc > t0 && tc < t1 {
+			sMin = b.s(tc)
+		}
+
+		kMin, kMax := b.weight*kernel(sMax), b.weight*kernel(sMin)
+		if b.weight < 0 {
+			kMin, kMax = kMax, kMin
+		}
+		low += kMin
+		high += kMax
+	}
+	if low > 0 || high < 0 {
+		return 0, false
+	}
+
+	if t1-t0 > minLength {
+		tm := (t0 + t1) / 2
+		fm := field(tm)
+		if t, ok := mb.root(balls, field, t0, tm, f0, fm, minLength); ok {
+			return t, true
+		}
+		return mb.root(balls, field, tm, t1, fm, f1, minLength)
+	}
+
+	// the root is isolated: it is where the field crosses the threshold
+	if (f0 < 0) == (f1 < 0) {
+		return 0, false
+	}
+	for i := 0; i < metaballRefinements; i++ {
+		tm := (t0 + t1) / 2
+		fm := field(tm)
+		if (fm < 0) == (f0 < 0) {
+			t0, f0 = tm, fm
+		} else {
+			t1 = tm
+		}
+	}
+
+	return t1, true
+}
+
+// normal returns the outward normal at p: the field decreases outwards
+func (mb Metaballs) normal(p Point3) Vec3 {
+	var n Vec3
+	for _, ball := range mb.Balls {
+		d := p.Sub(ball.Center)
+		r2 := ball.R * ball.R
+		s := DotProduct(d, d) / r2
+		if s >= 1 {
+			continue
+		}
+		n = n.Add(d.Scale(ball.Weight * 6 * (1 - s) * (1 - s) / r2))
+	}
+
+	return n.Unit()
+}
+
+func (mb Metaballs) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	var box *AABB
+	for _, ball := range mb.Balls {
+		if ball.Weight <= 0 {
+			continue
+		}
+
+		c := ball.Center.Vec3()
+		e := Vec3{X: ball.R, Y: ball.R, Z: ball.R}
+		b := AABB{Min: c.Sub(e), Max: c.Add(e)}
+		if box != nil {
+			b = box.union(b)
+		}
+		box = &b
+	}
+
+	return box != nil, box
+}
@@ -0,0 +1,208 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+)
+
+// motionBoxSamples is the number of instants between 2 keyframes at which the bounding box of a Motion is
+// computed
+const motionBoxSamples = 16
+
+// Keyframe is the placement of an animated HitTable at the time Time: it is scaled by Scale (a 0 component means
+// 1), rotated by Rotation (in degrees around X, then Y, then Z) and moved by Translation.
+type Keyframe struct {
+	Time        float64
+	Translation Vec3
+	Rotation    Vec3
+	Scale       Vec3
+}
+
+// scale returns the scale factors of the keyframe
+func (k Keyframe) scale() Vec3 {
+	s := k.Scale
+	if s.X == 0 {
+		s.X = 1
+	}
+	if s.Y == 0 {
+		s.Y = 1
+	}
+	if s.Z == 0 {
+		s.Z = 1
+	}
+
+	return s
+}
+
+// Matrix returns the transformation of the keyframe
+func (k Keyframe) Matrix() Matrix {
+	m, _ := k.matrices()
+	return m
+}
+
+// matrices returns the transformation of the keyframe and its inverse. The inverse of the rotation being its
+// transpose, the inverse is built directly instead of inverting the matrix.
+func (k Keyframe) matrices() (Matrix, Matrix) {
+	rotation := RotationZMatrix(k.Rotation.Z).Mult(RotationYMatrix(k.Rotation.Y)).Mult(RotationXMatrix(k.Rotation.X))
+	s := k.scale()
+	scale := [3]float64{s.X, s.Y, s.Z}
+	translation := [3]float64{k.Translation.X, k.Translation.Y, k.Translation.Z}
+
+	var m, inverse Matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			m[i][j] = rotation[i][j] * scale[j]
+			inverse[i][j] = rotation[j][i] / scale[i]
+		}
+		m[i][3] = translation[i]
+	}
+	for i := 0; i < 3; i++ {
+		inverse[i][3] = -(inverse[i][0]*translation[0] + inverse[i][1]*translation[1] + inverse[i][2]*translation[2])
+	}
+
+	return m, inverse
+}
+
+// Motion animates a HitTable by interpolating its placement between keyframes: every ray is intersected with the
+// HitTable placed as it is at the time of the ray. The HitTable stays at the first (resp. last) keyframe before
+// (resp. after) it.
+// The translation, rotation and scale are interpolated separately, so that the rotations turn instead of
+// shearing the HitTable.
+type Motion struct {
+	HitTable  HitTable
+	Keyframes []Keyframe
+}
+
+// NewMotion returns the HitTable animated by the keyframes (in any order)
+func NewMotion(h HitTable, keyframes ...Keyframe) Motion {
+	ks := append([]Keyframe(nil), keyframes...)
+	sort.SliceStable(ks, func(i, j int) bool {
+		return ks[i].Time < ks[j].Time
+	})
+
+	return Motion{HitTable: h, Keyframes: ks}
+}
+
+// at returns the keyframe interpolated at the time t
+func (mo Motion) at(t float64) Keyframe {
+	ks := mo.Keyframes
+	i := sort.Search(len(ks), func(i int) bool {
+		return ks[i].Time > t
+	})
+
+	switch {
+	case len(ks) == 0:
+		return Keyframe{Time: t}
+	case i == 0:
+		return ks[0]
+	case i == len(ks):
+		return ks[len(ks)-1]
+	}
+
+	k0, k1 := ks[i-1], ks[i]
+	f := (t - k0.Time) / (k1.Time - k0.Time)
+	lerp := func(a, b Vec3) Vec3 {
+		return a.Add(b.Sub(a).Scale(f))
+	}
+
+	return Keyframe{
+		Time:        t,
+		Translation: lerp(k0.Translation, k1.Translation),
+		Rotation:    lerp(k0.Rotation, k1.Rotation),
+		Scale:       lerp(k0.scale(), k1.scale()),
+	}
+}
+
+// Hit intersects the ray with the HitTable placed as it is at the time of the ray
+func (mo Motion) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	m, inverse := mo.at(r.Time).matrices()
+
+	or := *r
+	or.Origin = inverse.Point(r.Origin)
+	or.Dir = inverse.Vec3(r.Dir)
+
+	hit, hr := mo.HitTable.Hit(&or, tMin, tMax)
+	if !hit {
+		return false, nil
+	}
+
+	return true, Transform{matrix: m, inverse: inverse}.toWorld(hr)
+}
+
+// BoundingBox contains the boxes of the HitTable at the keyframes and at many instants between them. The rotations
+// move the HitTable along arcs which may leave the boxes between 2 instants: the boxes are padded by the largest
+// distance between the arcs and their chords.
+func (mo Motion) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	if tm1 < tm0 {
+		tm0, tm1 = tm1, tm0
+	}
+
+	ok, inner := mo.HitTable.BoundingBox(tm0, tm1)
+	if !ok {
+		return false, nil
+	}
+	// distance of the farthest corner of the box from the origin of the HitTable
+	radius := Vec3{
+		X: math.Max(math.Abs(inner.Min.X), math.Abs(inner.Max.X)),
+		Y: math.Max(math.Abs(inner.Min.Y), math.Abs(inner.Max.Y)),
+		Z: math.Max(math.Abs(inner.Min.Z), math.Abs(inner.Max.Z)),
+	}.Length()
+
+	times := []float64{tm0}
+	for _, k := range mo.Keyframes {
+		if k.Time > tm0 && k.Time < tm1 {
+			times = append(times, k.Time)
+		}
+	}
+	times = append(times, tm1)
+
+	var box *AABB
+	for i := 0; i+1 < len(times); i++ {
+		step := (times[i+1] - times[i]) / motionBoxSamples
+		pad := mo.chordDistance((times[i]+times[i+1])/2, step, radius)
+		for s := 0; s <= motionBoxSamples; s++ {
+			t := times[i] + step*float64(s)
+			ok, b := NewTransform(mo.HitTable, mo.at(t).Matrix()).BoundingBox(tm0, tm1)
+			if !ok {
+				return false, nil
+			}
+			b.Min = b.Min.Sub(Vec3{X: pad, Y: pad, Z: pad})
+			b.Max = b.Max.Add(Vec3{X: pad, Y: pad, Z: pad})
+			if box != nil {
+				*b = box.union(*b)
+			}
+			box = b
+		}
+	}
+
+	return true, box
+}
+
+// chordDistance bounds the distance between the path of a point of the HitTable (at most radius away from its
+// origin) during step around the time t and the chord joining the ends of that path. The path being smooth, the
+// distance is at most step²/8 times its second derivative, which comes from the rotation speed w (each rotation
+// around an axis accelerates the point by at most its speed times its own speed) and from the scale speed.
+func (mo Motion) chordDistance(t, step, radius float64) float64 {
+	ks := mo.Keyframes
+	i := sort.Search(len(ks), func(i int) bool {
+		return ks[i].Time > t
+	})
+	if i == 0 || i == len(ks) {
+		return 0
+	}
+
+	k0, k1 := ks[i-1], ks[i]
+	d := k1.Time - k0.Time
+	rotation := k1.Rotation.Sub(k0.Rotation)
+	w := (math.Abs(rotation.X) + math.Abs(rotation.Y) + math.Abs(rotation.Z)) * math.Pi / 180 / d
+
+	s0, s1 := k0.scale(), k1.scale()
+	var scale float64
+	for _, s := range [2]Vec3{s0, s1} {
+		scale = math.Max(scale, math.Max(math.Abs(s.X), math.Max(math.Abs(s.Y), math.Abs(s.Z))))
+	}
+	ds := s1.Sub(s0)
+	speed := math.Max(math.Abs(ds.X), math.Max(math.Abs(ds.Y), math.Abs(ds.Z))) / d
+
+	return step * step / 8 * (w*w*scale + 2*w*speed) * radius
+}
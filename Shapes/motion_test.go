@@ -0,0 +1,119 @@
+package shapes
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestKeyframeInverse(t *testing.T) {
+	keyframes := []Keyframe{
+		{},
+		{Translation: Vec3{X: 1, Y: -2, Z: 3}},
+		{Rotation: Vec3{X: 30, Y: -45, Z: 120}, Scale: Vec3{X: 2, Y: 0.5, Z: 3}},
+		{Translation: Vec3{X: -4, Z: 2}, Rotation: Vec3{Y: 90}, Scale: Vec3{X: -1, Y: 1}},
+	}
+
+	for _, k := range keyframes {
+		m, got := k.matrices()
+		ok, want := m.Inverse()
+		if !ok {
+			t.Fatalf("%+v: matrix not invertible", k)
+		}
+		if wantM := TranslationMatrix(k.Translation).Mult(RotationZMatrix(k.Rotation.Z)).Mult(RotationYMatrix(k.Rotation.Y)).
+			Mult(RotationXMatrix(k.Rotation.X)).Mult(ScaleMatrix(k.scale())); !closeMatrices(m, wantM) {
+			t.Fatalf("%+v: matrix = %v, want %v", k, m, wantM)
+		}
+		if !closeMatrices(got, want) {
+			t.Fatalf("%+v: inverse = %v, want %v", k, got, want)
+		}
+	}
+}
+
+func closeMatrices(a, b Matrix) bool {
+	for i := range a {
+		for j := range a[i] {
+			if math.Abs(a[i][j]-b[i][j]) > 1e-12 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func TestMotionHit(t *testing.T) {
+	box := NewBox(Point3{X: -0.5, Z: -0.5}, Point3{X: 0.5, Y: 1, Z: 0.5}, nil)
+	mo := NewMotion(box,
+		Keyframe{Time: 0, Translation: Vec3{X: -2}},
+		Keyframe{Time: 0.5, Rotation: Vec3{Y: 45, Z: 10}, Scale: Vec3{X: 1.5}},
+		Keyframe{Time: 1, Translation: Vec3{X: 2, Y: 0.5}, Rotation: Vec3{Y: 90}},
+	)
+
+	rnd := rand.New(rand.NewSource(1))
+	hits := 0
+	for i := 0; i < 10000; i++ {
+		r := Ray{
+			Origin: Point3{X: 8*rnd.Float64() - 4, Y: 8*rnd.Float64() - 4, Z: 5},
+			Dir:    Vec3{X: rnd.Float64() - 0.5, Y: rnd.Float64() - 0.5, Z: -1},
+			Time:   1.2*rnd.Float64() - 0.1,
+		}
+
+		hit, hr := mo.Hit(&r, 0.001, math.Inf(1))
+		wantHit, want := NewTransform(box, mo.at(r.Time).Matrix()).Hit(&r, 0.001, math.Inf(1))
+		if hit != wantHit {
+			t.Fatalf("ray %+v: hit = %v, want %v", r, hit, wantHit)
+		}
+		if !hit {
+			continue
+		}
+		hits++
+		if math.Abs(hr.T-want.T) > 1e-9 || hr.P.Sub(want.P).Length() > 1e-9 || hr.Normal.Sub(want.Normal).Length() > 1e-9 {
+			t.Fatalf("ray %+v: hit %+v, want %+v", r, hr, want)
+		}
+	}
+
+	if hits == 0 {
+		t.Error("no ray hit the box")
+	}
+}
+
+func BenchmarkMotionHit(b *testing.B) {
+	mo := NewMotion(Sphere{R: 1},
+		Keyframe{Time: 0, Translation: Vec3{X: -1}},
+		Keyframe{Time: 1, Translation: Vec3{X: 1}, Rotation: Vec3{Y: 90}},
+	)
+	r := Ray{Origin: Point3{Z: 5}, Dir: Vec3{Z: -1}}
+
+	for i := 0; i < b.N; i++ {
+		r.Time = float64(i%100) / 100
+		mo.Hit(&r, 0.001, math.Inf(1))
+	}
+}
+
+func TestMotionBoundingBox(t *testing.T) {
+	// a long thin box: its ends move along arcs which leave the boxes sampled on both sides of their extremes
+	bar := NewBox(Point3{X: -2, Y: -0.01, Z: -0.01}, Point3{X: 2, Y: 0.01, Z: 0.01}, nil)
+	motions := []Motion{
+		NewMotion(bar, Keyframe{Time: 0}, Keyframe{Time: 1, Rotation: Vec3{Y: 100}}),
+		NewMotion(bar, Keyframe{Time: 0, Rotation: Vec3{Z: -30}}, Keyframe{Time: 1, Translation: Vec3{X: 1}, Rotation: Vec3{X: 40, Y: 170, Z: 60}, Scale: Vec3{X: 2}}),
+		NewMotion(Translate(bar, Vec3{X: 3}), Keyframe{Time: 0.2}, Keyframe{Time: 0.4, Rotation: Vec3{Y: 360}}, Keyframe{Time: 2, Rotation: Vec3{Y: 370}}),
+	}
+
+	for i, mo := range motions {
+		for _, interval := range [][2]float64{{0, 1}, {0.1, 0.3}, {0.25, 0.35}} {
+			ok, box := mo.BoundingBox(interval[0], interval[1])
+			if !ok {
+				t.Fatalf("motion %d: no bounding box", i)
+			}
+
+			for s := 0; s <= 10000; s++ {
+				tm := interval[0] + (interval[1]-interval[0])*float64(s)/10000
+				_, b := NewTransform(mo.HitTable, mo.at(tm).Matrix()).BoundingBox(tm, tm)
+				if !contains(*box, *b) {
+					t.Fatalf("motion %d over %v: box %v does not contain the box %v at %v", i, interval, *box, *b, tm)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package shapes
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// objMaxFuzz is the fuzz of the roughest Metal converted from a MTL material (a Metal with a fuzz of 1 or more is
+// not fuzzy at all)
+const objMaxFuzz = 0.99
+
+// DefaultOBJMaterial is used for the faces which do not reference any material
+var DefaultOBJMaterial Material = Lambertian{Albedo: NewSolidColor(Color{R: 0.7, G: 0.7, B: 0.7})}
+
+// objVertex holds the (0 based) indices of the position, texture coordinates and normal of a face vertex.
+// A missing texture coordinates or normal index is -1.
+type objVertex struct {
+	v, vt, vn int
+}
+
+// objFace is a polygon of an OBJ file
+type objFace struct {
+	vertices []objVertex
+	material int // index in objFile.materials
+}
+
+// objGroup is a named set of faces (an 'o' or 'g' statement)
+type objGroup struct {
+	name  string
+	faces []objFace
+}
+
+// objFile is the raw content of an OBJ file
+type objFile struct {
+	positions []Point3
+	normals   []Vec3
+	uvs       []UV
+	groups    []*objGroup
+	materials []Material
+}
+
+// LoadOBJ reads the Wavefront OBJ file at path (and the MTL files it references) and returns the geometry as a
+// list holding one Mesh per object/group. Polygons are triangulated as fans.
+//
+// The materials are mapped as follows:
+//   - d < 1 (or Tr > 0) gives a Dielectric with the refraction index Ni
+//   - illum 3, or a specular color Ks brighter than the diffuse color Kd, gives a Metal (the fuzz comes from Ns)
+//   - anything else is a Lambertian using map_Kd if present, Kd otherwise
+func LoadOBJ(path string) (HitTableList, error) {
+	obj, err := parseOBJ(path)
+	if err != nil {
+		return HitTableList{}, err
+	}
+
+	var world []HitTable
+	for _, g := range obj.groups {
+		if len(g.faces) > 0 {
+			world = append(world, obj.mesh(g))
+		}
+	}
+
+	return HitTableList{Hits: world}, nil
+}
+
+// mesh builds the mesh of a group. Every distinct position/texture coordinates/normal combination becomes a
+// vertex of the mesh. The normals (resp. texture coordinates) are dropped if some vertices do not have one.
+func (obj *objFile) mesh(g *objGroup) *Mesh {
+	var (
+		positions       []Point3
+		normals         []Vec3
+		uvs             []UV
+		indices         []int32
+		materialIndices []int32
+		hasNormals      = true
+		hasUVs          = true
+		vertices        = map[objVertex]int32{}
+	)
+
+	for _, f := range g.faces {
+		for _, v := range f.vertices {
+			if _, ok := vertices[v]; ok {
+				continue
+			}
+
+			vertices[v] = int32(len(positions))
+			positions = append(positions, obj.positions[v.v])
+			if v.vn >= 0 {
+				normals = append(normals, obj.normals[v.vn])
+			} else {
+				hasNormals = false
+			}
+			if v.vt >= 0 {
+				uvs = append(uvs, obj.uvs[v.vt])
+			} else {
+				hasUVs = false
+			}
+		}
+
+		for i := 1; i+1 < len(f.vertices); i++ {
+			indices = append(indices, vertices[f.vertices[0]], vertices[f.vertices[i]], vertices[f.vertices[i+1]])
+			materialIndices = append(materialIndices, int32(f.material))
+		}
+	}
+
+	if !hasNormals {
+		normals = nil
+	}
+	if !hasUVs {
+		uvs = nil
+	}
+
+	return NewMesh(positions, normals, uvs, indices, obj.materials, materialIndices)
+}
+
+// parseOBJ reads the OBJ file at path
+func parseOBJ(path string) (*objFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readOBJ(f, path)
+}
+
+// readOBJ reads an OBJ file from r. path is used in the errors and to find the MTL files.
+func readOBJ(r io.Reader, path string) (*objFile, error) {
+	// the material 0 is used by the faces which do not reference any material
+	obj := &objFile{materials: []Material{DefaultOBJMaterial}}
+	materials := map[string]Material{}
+	materialIndices := map[string]int{}
+	material := 0
+	group := &objGroup{}
+	obj.groups = append(obj.groups, group)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			v, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+			}
+			obj.positions = append(obj.positions, Point3{X: v[0], Y: v[1], Z: v[2]})
+		case "vn":
+			v, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+			}
+			obj.normals = append(obj.normals, Vec3{X: v[0], Y: v[1], Z: v[2]})
+		case "vt":
+			v, err := parseFloats(fields[1:], 1)
+			if err != nil {
+				return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+			}
+			uv := UV{U: v[0]}
+			if len(v) > 1 {
+				uv.V = v[1]
+			}
+			obj.uvs = append(obj.uvs, uv)
+		case "f":
+			face, err := obj.parseFace(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+			}
+			face.material = material
+			group.faces = append(group.faces, face)
+		case "o", "g":
+			group = &objGroup{name: strings.Join(fields[1:], " ")}
+			obj.groups = append(obj.groups, group)
+		case "usemtl":
+			name := strings.Join(fields[1:], " ")
+			m, ok := materials[name]
+			if !ok {
+				material = 0
+				break
+			}
+			if material, ok = materialIndices[name]; !ok {
+				material = len(obj.materials)
+				materialIndices[name] = material
+				obj.materials = append(obj.materials, m)
+			}
+		case "mtllib":
+			for _, name := range fields[1:] {
+				if err := loadMTL(filepath.Join(filepath.Dir(path), name), materials); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return obj, nil
+}
+
+// parseFace parses the vertices of a face: v, v/vt, v//vn or v/vt/vn (indices are 1 based, negative indices
+// are relative to the end of the lists)
+func (obj *objFile) parseFace(fields []string) (objFace, error) {
+	if len(fields) < 3 {
+		return objFace{}, fmt.Errorf("face with %d vertices", len(fields))
+	}
+
+	face := objFace{vertices: make([]objVertex, len(fields))}
+	for i, field := range fields {
+		parts := strings.Split(field, "/")
+		vertex := objVertex{v: -1, vt: -1, vn: -1}
+
+		var err error
+		if vertex.v, err = objIndex(parts[0], len(obj.positions)); err != nil {
+			return objFace{}, err
+		}
+		if len(parts) > 1 && parts[1] != "" {
+			if vertex.vt, err = objIndex(parts[1], len(obj.uvs)); err != nil {
+				return objFace{}, err
+			}
+		}
+		if len(parts) > 2 && parts[2] != "" {
+			if vertex.vn, err = objIndex(parts[2], len(obj.normals)); err != nil {
+				return objFace{}, err
+			}
+		}
+
+		face.vertices[i] = vertex
+	}
+
+	return face, nil
+}
+
+// objIndex converts an OBJ index into a 0 based index in a list of size n
+func objIndex(s string, n int) (int, error) {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+
+	if i < 0 {
+		i += n
+	} else {
+		i--
+	}
+
+	if i < 0 || i >= n {
+		return 0, fmt.Errorf("index %v out of range", s)
+	}
+
+	return i, nil
+}
+
+// parseFloats parses at least min floats
+func parseFloats(fields []string, min int) ([]float64, error) {
+	if len(fields) < min {
+		return nil, fmt.Errorf("expected %d values, got %d", min, len(fields))
+	}
+
+	values := make([]float64, len(fields))
+	for i := range fields {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// mtlMaterial is the raw description of a material in a MTL file
+type mtlMaterial struct {
+	kd, ks     Color
+	ns, ni, d  float64
+	illum      int
+	mapKd      string
+	hasOpacity bool
+}
+
+// loadMTL reads the MTL file at path and adds its materials to the map
+func loadMTL(path string, materials map[string]Material) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		name string
+		mtl  *mtlMaterial
+	)
+
+	add := func() error {
+		if mtl == nil {
+			return nil
+		}
+
+		m, err := mtl.material(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		materials[name] = m
+		return nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if fields[0] == "newmtl" {
+			if err := add(); err != nil {
+				return err
+			}
+			name = strings.Join(fields[1:], " ")
+			mtl = &mtlMaterial{kd: Color{R: 0.7, G: 0.7, B: 0.7}, ni: 1, d: 1}
+			continue
+		}
+
+		if mtl == nil {
+			continue
+		}
+
+		if err := mtl.parse(fields); err != nil {
+			return fmt.Errorf("%v:%d: %v", path, line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%v: %v", path, err)
+	}
+
+	return add()
+}
+
+// parse reads one statement of a material definition
+func (mtl *mtlMaterial) parse(fields []string) error {
+	switch fields[0] {
+	case "Kd", "Ks":
+		v, err := parseFloats(fields[1:], 3)
+		if err != nil {
+			return err
+		}
+		if fields[0] == "Kd" {
+			mtl.kd = Color{R: v[0], G: v[1], B: v[2]}
+		} else {
+			mtl.ks = Color{R: v[0], G: v[1], B: v[2]}
+		}
+	case "Ns", "Ni", "d", "Tr":
+		v, err := parseFloats(fields[1:], 1)
+		if err != nil {
+			return err
+		}
+		switch fields[0] {
+		case "Ns":
+			mtl.ns = v[0]
+		case "Ni":
+			mtl.ni = v[0]
+		case "d":
+			mtl.d = v[0]
+			mtl.hasOpacity = true
+		case "Tr":
+			if !mtl.hasOpacity {
+				mtl.d = 1 - v[0]
+			}
+		}
+	case "illum":
+		i, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil {
+			return err
+		}
+		mtl.illum = i
+	case "map_Kd":
+		// options (-s, -o...) come before the file name
+		mtl.mapKd = fields[len(fields)-1]
+	}
+
+	return nil
+}
+
+// material converts the MTL description into a Material (see LoadOBJ)
+func (mtl *mtlMaterial) material(dir string) (Material, error) {
+	if mtl.d < 1 {
+		return Dielectric{Ri: mtl.ni}, nil
+	}
+
+	if mtl.illum == 3 || maxComponent(mtl.ks) > maxComponent(mtl.kd) {
+		albedo := mtl.ks
+		if maxComponent(albedo) == 0 {
+			albedo = mtl.kd
+		}
+		return Metal{Albedo: albedo, Fuzz: math.Min(math.Sqrt(2/(math.Max(mtl.ns, 0)+2)), objMaxFuzz)}, nil
+	}
+
+	if mtl.mapKd != "" {
+		t, err := LoadImageTexture(filepath.Join(dir, filepath.FromSlash(mtl.mapKd)))
+		if err != nil {
+			return nil, err
+		}
+		return Lambertian{Albedo: t}, nil
+	}
+
+	return Lambertian{Albedo: NewSolidColor(mtl.kd)}, nil
+}
+
+// maxComponent returns the highest of the R/G/B components
+func maxComponent(c Color) float64 {
+	return math.Max(c.R, math.Max(c.G, c.B))
+}
@@ -0,0 +1,180 @@
+package shapes
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadOBJ(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		indices   []int32
+		positions int
+		normals   bool
+		uvs       bool
+		err       bool
+	}{
+		{
+			name:      "triangle",
+			src:       "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n",
+			indices:   []int32{0, 1, 2},
+			positions: 3,
+		},
+		{
+			name:      "quad as a fan",
+			src:       "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nf 1 2 3 4\n",
+			indices:   []int32{0, 1, 2, 0, 2, 3},
+			positions: 4,
+		},
+		{
+			name:      "negative indices",
+			src:       "v 0 0 0\nv 1 0 0\nv 0 1 0\nf -3 -2 -1\nv 1 1 0\nf -3 -1 -2\n",
+			indices:   []int32{0, 1, 2, 1, 3, 2},
+			positions: 4,
+		},
+		{
+			name: "texture coordinates and normals",
+			src: "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0 0\nvt 1 0\nvt 0 1\nvn 0 0 1\n" +
+				"f 1/1/1 2/2/1 3/3/1\n",
+			indices:   []int32{0, 1, 2},
+			positions: 3,
+			normals:   true,
+			uvs:       true,
+		},
+		{
+			name:      "normals only",
+			src:       "v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0 0 1\nf 1//1 2//1 3//1\n",
+			indices:   []int32{0, 1, 2},
+			positions: 3,
+			normals:   true,
+		},
+		{
+			name:      "vertex shared with different normals",
+			src:       "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 1 1 0\nvn 0 0 1\nvn 0 1 0\nf 1//1 2//1 3//1\nf 2//2 4//2 3//2\n",
+			indices:   []int32{0, 1, 2, 3, 4, 5},
+			positions: 6,
+			normals:   true,
+		},
+		{
+			name:      "missing normal drops all normals",
+			src:       "v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0 0 1\nf 1//1 2 3//1\n",
+			indices:   []int32{0, 1, 2},
+			positions: 3,
+		},
+		{name: "index out of range", src: "v 0 0 0\nv 1 0 0\nf 1 2 3\n", err: true},
+		{name: "zero index", src: "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 0 1 2\n", err: true},
+		{name: "face with 2 vertices", src: "v 0 0 0\nv 1 0 0\nf 1 2\n", err: true},
+		{name: "bad vertex", src: "v 0 0\n", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := readOBJ(strings.NewReader(tt.src), "test.obj")
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := obj.mesh(obj.groups[0])
+			if !reflect.DeepEqual(m.Indices, tt.indices) {
+				t.Errorf("indices = %v, want %v", m.Indices, tt.indices)
+			}
+			if len(m.Positions) != tt.positions {
+				t.Errorf("%d positions, want %d", len(m.Positions), tt.positions)
+			}
+			if (len(m.Normals) > 0) != tt.normals {
+				t.Errorf("%d normals, want normals: %v", len(m.Normals), tt.normals)
+			}
+			if (len(m.UVs) > 0) != tt.uvs {
+				t.Errorf("%d uvs, want uvs: %v", len(m.UVs), tt.uvs)
+			}
+		})
+	}
+}
+
+func TestReadOBJGroups(t *testing.T) {
+	src := "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 1 1 0\no a\nf 1 2 3\ng b\nf 2 4 3\nf 1 2 4\n"
+	obj, err := readOBJ(strings.NewReader(src), "test.obj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var faces []int
+	for _, g := range obj.groups {
+		names = append(names, g.name)
+		faces = append(faces, len(g.faces))
+	}
+	if want := []string{"", "a", "b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("groups = %q, want %q", names, want)
+	}
+	if want := []int{0, 1, 2}; !reflect.DeepEqual(faces, want) {
+		t.Errorf("faces = %v, want %v", faces, want)
+	}
+}
+
+func TestLoadOBJMaterials(t *testing.T) {
+	dir := t.TempDir()
+	mtl := "newmtl red\nKd 0.8 0.1 0.1\n\nnewmtl glass\nd 0.5\nNi 1.5\n\nnewmtl mirror\nillum 3\nKs 0.9 0.9 0.9\nNs 1000\n\nnewmtl rough\nillum 3\nKs 0.5 0.5 0.5\nNs 0\n"
+	obj := "mtllib test.mtl\nv 0 0 0\nv 1 0 0\nv 0 1 0\n" +
+		"f 1 2 3\nusemtl red\nf 1 2 3\nusemtl glass\nf 1 2 3\nusemtl mirror\nf 1 2 3\nusemtl unknown\nf 1 2 3\nusemtl red\nf 1 2 3\nusemtl rough\nf 1 2 3\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.mtl"), []byte(mtl), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.obj"), []byte(obj), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	world, err := LoadOBJ(filepath.Join(dir, "test.obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(world.Hits) != 1 {
+		t.Fatalf("%d meshes, want 1", len(world.Hits))
+	}
+
+	m := world.Hits[0].(*Mesh)
+	if want := []int32{0, 1, 2, 3, 0, 1, 4}; !reflect.DeepEqual(m.MaterialIndices, want) {
+		t.Errorf("material indices = %v, want %v", m.MaterialIndices, want)
+	}
+	if len(m.Materials) != 5 {
+		t.Fatalf("%d materials, want 5", len(m.Materials))
+	}
+	if m.Materials[0] != DefaultOBJMaterial {
+		t.Errorf("material 0 = %#v, want DefaultOBJMaterial", m.Materials[0])
+	}
+	if _, ok := m.Materials[1].(Lambertian); !ok {
+		t.Errorf("red = %T, want Lambertian", m.Materials[1])
+	}
+	if d, ok := m.Materials[2].(Dielectric); !ok || d.Ri != 1.5 {
+		t.Errorf("glass = %#v, want Dielectric with Ri 1.5", m.Materials[2])
+	}
+	if mirror, ok := m.Materials[3].(Metal); !ok || mirror.Fuzz > 0.05 {
+		t.Errorf("mirror = %#v, want a Metal with a small fuzz", m.Materials[3])
+	}
+	// Ns 0 is the roughest metal, which must stay fuzzy
+	if rough, ok := m.Materials[4].(Metal); !ok || rough.Fuzz < 0.9 || rough.Fuzz >= 1 {
+		t.Errorf("rough = %#v, want a Metal with a fuzz close to but below 1", m.Materials[4])
+	}
+}
+
+func TestLoadOBJMissingMTL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.obj")
+	if err := ioutil.WriteFile(path, []byte("mtllib missing.mtl\nv 0 0 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadOBJ(path); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,119 @@
+package shapes
+
+import (
+	"math"
+)
+
+// Plane is an infinite plane going through Point and orthogonal to Normal.
+// The u, v coordinates are the coordinates of the hit point in the plane (in world units, starting at Point),
+// so that textures repeat along it.
+// A Plane has no bounding box: the bounding volume hierarchies keep it aside and test it on its own.
+type Plane struct {
+	Point    Point3
+	Normal   Vec3
+	Material Material
+}
+
+func (pl Plane) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	n := pl.Normal.Unit()
+	denom := DotProduct(n, r.Dir)
+	if denom == 0 {
+		return false, nil
+	}
+
+	t := DotProduct(pl.Point.Sub(r.Origin), n) / denom
+	if t < tMin || t > tMax {
+		return false, nil
+	}
+
+	p := r.PointAt(t)
+	tu, tv := orthonormalBasis(n)
+	d := p.Sub(pl.Point)
+	hr := HitRecord{
+		T:      t,
+		P:      p,
+		Normal: n,
+		Mat:    pl.Material,
+		U:      DotProduct(d, tu),
+		V:      DotProduct(d, tv),
+	}
+
+	return true, &hr
+}
+
+func (pl Plane) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	return false, nil
+}
+
+// Disk is a flat disk of radius R centered on Center and orthogonal to Normal.
+// u is the angle around the center (in [0, 1]) and v the distance to the center (0 at the center, 1 on the
+// edge).
+type Disk struct {
+	Center   Point3
+	Normal   Vec3
+	R        float64
+	Material Material
+}
+
+func (d Disk) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	n := d.Normal.Unit()
+	denom := DotProduct(n, r.Dir)
+	if denom == 0 {
+		return false, nil
+	}
+
+	t := DotProduct(d.Center.Sub(r.Origin), n) / denom
+	if t < tMin || t > tMax {
+		return false, nil
+	}
+
+	p := r.PointAt(t)
+	cp := p.Sub(d.Center)
+	dist := cp.Length()
+	if dist > d.R {
+		return false, nil
+	}
+
+	tu, tv := orthonormalBasis(n)
+	phi := math.Atan2(DotProduct(cp, tv), DotProduct(cp, tu))
+	if phi < 0 {
+		phi += 2 * math.Pi
+	}
+
+	hr := HitRecord{
+		T:      t,
+		P:      p,
+		Normal: n,
+		Mat:    d.Material,
+		U:      phi / (2 * math.Pi),
+		V:      dist / d.R,
+	}
+
+	return true, &hr
+}
+
+func (d Disk) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	// extent of the disk along each axis
+	n := d.Normal.Unit()
+	e := Vec3{
+		X: d.R * math.Sqrt(math.Max(0, 1-n.X*n.X)),
+		Y: d.R * math.Sqrt(math.Max(0, 1-n.Y*n.Y)),
+		Z: d.R * math.Sqrt(math.Max(0, 1-n.Z*n.Z)),
+	}
+
+	box := AABB{Min: d.Center.Vec3().Sub(e), Max: d.Center.Vec3().Add(e)}.padded()
+	return true, &box
+}
+
+// orthonormalBasis returns 2 unit vectors which are orthogonal to each other and to the unit vector n
+func orthonormalBasis(n Vec3) (Vec3, Vec3) {
+	// the axis the least aligned with n avoids a degenerated cross product
+	a := Vec3{X: 1}
+	if math.Abs(n.X) > 0.9 {
+		a = Vec3{Y: 1}
+	}
+
+	u := Cross(a, n).Unit()
+	v := Cross(n, u)
+	return u, v
+}
@@ -0,0 +1,424 @@
+package shapes
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// plyProperty is a property of a PLY element. For a list, countType is the type of the number of items.
+type plyProperty struct {
+	name      string
+	valueType string
+	countType string
+}
+
+// plyElement is an element (vertex, face...) declared in the header of a PLY file
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+// plyReader reads the values of the body of a PLY file
+type plyReader interface {
+	read(valueType string) (float64, error)
+}
+
+// LoadPLY reads the PLY file (ascii, binary little endian or binary big endian) at path and returns its mesh.
+// The optional normals (nx, ny, nz), texture coordinates (u, v or s, t) and colors (red, green, blue) of the
+// vertices are loaded as well. Polygons are triangulated as fans.
+// All the triangles use the material m. When m is nil, a Lambertian using the vertex colors (or
+// DefaultMeshMaterial when there are none) is used.
+func LoadPLY(path string, m Material) (*Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	format, elements, err := readPLYHeader(br)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	var r plyReader
+	switch format {
+	case "ascii":
+		s := bufio.NewScanner(br)
+		s.Split(bufio.ScanWords)
+		r = &plyASCIIReader{scanner: s}
+	case "binary_little_endian":
+		r = &plyBinaryReader{r: br, order: binary.LittleEndian}
+	case "binary_big_endian":
+		r = &plyBinaryReader{r: br, order: binary.BigEndian}
+	default:
+		return nil, fmt.Errorf("%v: unknown format %v", path, format)
+	}
+
+	var (
+		positions []Point3
+		normals   []Vec3
+		uvs       []UV
+		colors    []Color
+		indices   []int32
+	)
+
+	for _, e := range elements {
+		switch e.name {
+		case "vertex":
+			positions, normals, uvs, colors, err = readPLYVertices(r, e)
+		case "face":
+			indices, err = readPLYFaces(r, e, len(positions))
+		default:
+			err = skipPLYElement(r, e)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v: %v", path, e.name, err)
+		}
+	}
+
+	if m == nil {
+		m = DefaultMeshMaterial
+		if len(colors) > 0 {
+			m = Lambertian{Albedo: VertexColorTexture{Fallback: Color{R: 0.7, G: 0.7, B: 0.7}}}
+		}
+	}
+
+	mesh := NewMesh(positions, normals, uvs, indices, []Material{m}, nil)
+	mesh.Colors = colors
+	return mesh, nil
+}
+
+// readPLYHeader reads the header up to (and including) the end_header line
+func readPLYHeader(br *bufio.Reader) (string, []*plyElement, error) {
+	var (
+		format   string
+		elements []*plyElement
+	)
+
+	for line := 0; ; line++ {
+		s, err := br.ReadString('\n')
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid header: %v", err)
+		}
+
+		fields := strings.Fields(s)
+		if line == 0 {
+			if len(fields) != 1 || fields[0] != "ply" {
+				return "", nil, fmt.Errorf("not a ply file")
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 {
+				return "", nil, fmt.Errorf("invalid format line")
+			}
+			format = fields[1]
+		case "element":
+			if len(fields) < 3 {
+				return "", nil, fmt.Errorf("invalid element line")
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return "", nil, err
+			}
+			if count < 0 {
+				return "", nil, fmt.Errorf("invalid count %v of the element %v", count, fields[1])
+			}
+			elements = append(elements, &plyElement{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return "", nil, fmt.Errorf("property outside of an element")
+			}
+			var p plyProperty
+			switch {
+			case len(fields) == 5 && fields[1] == "list":
+				p = plyProperty{name: fields[4], valueType: fields[3], countType: fields[2]}
+			case len(fields) == 3:
+				p = plyProperty{name: fields[2], valueType: fields[1]}
+			default:
+				return "", nil, fmt.Errorf("invalid property line")
+			}
+			if _, ok := plyTypeSizes[p.valueType]; !ok {
+				return "", nil, fmt.Errorf("unknown type %v", p.valueType)
+			}
+			e := elements[len(elements)-1]
+			e.properties = append(e.properties, p)
+		case "end_header":
+			return format, elements, nil
+		}
+	}
+}
+
+// readPLYVertices reads the vertex element. The normals (resp. uvs and colors) are nil when the vertices do not
+// have them.
+func readPLYVertices(r plyReader, e *plyElement) ([]Point3, []Vec3, []UV, []Color, error) {
+	has := map[string]bool{}
+	for _, p := range e.properties {
+		has[p.name] = true
+	}
+
+	positions := make([]Point3, e.count)
+	var (
+		normals []Vec3
+		uvs     []UV
+		colors  []Color
+	)
+	if has["nx"] && has["ny"] && has["nz"] {
+		normals = make([]Vec3, e.count)
+	}
+	if (has["u"] && has["v"]) || (has["s"] && has["t"]) || (has["texture_u"] && has["texture_v"]) {
+		uvs = make([]UV, e.count)
+	}
+	if has["red"] && has["green"] && has["blue"] {
+		colors = make([]Color, e.count)
+	}
+
+	for i := 0; i < e.count; i++ {
+		for _, p := range e.properties {
+			if p.countType != "" {
+				if err := skipPLYList(r, p); err != nil {
+					return nil, nil, nil, nil, err
+				}
+				continue
+			}
+
+			v, err := r.read(p.valueType)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+
+			switch p.name {
+			case "x":
+				positions[i].X = v
+			case "y":
+				positions[i].Y = v
+			case "z":
+				positions[i].Z = v
+			case "nx", "ny", "nz":
+				if normals != nil {
+					switch p.name {
+					case "nx":
+						normals[i].X = v
+					case "ny":
+						normals[i].Y = v
+					case "nz":
+						normals[i].Z = v
+					}
+				}
+			case "u", "s", "texture_u":
+				if uvs != nil {
+					uvs[i].U = v
+				}
+			case "v", "t", "texture_v":
+				if uvs != nil {
+					uvs[i].V = v
+				}
+			case "red", "green", "blue":
+				if colors != nil {
+					v = plyColorComponent(v, p.valueType)
+					switch p.name {
+					case "red":
+						colors[i].R = v
+					case "green":
+						colors[i].G = v
+					case "blue":
+						colors[i].B = v
+					}
+				}
+			}
+		}
+	}
+
+	return positions, normals, uvs, colors, nil
+}
+
+// readPLYFaces reads the face element and returns the indices of its triangles
+func readPLYFaces(r plyReader, e *plyElement, vertexCount int) ([]int32, error) {
+	indices := make([]int32, 0, 3*e.count)
+	polygon := make([]int32, 0, 4)
+
+	for i := 0; i < e.count; i++ {
+		for _, p := range e.properties {
+			if p.countType == "" {
+				if _, err := r.read(p.valueType); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if p.name != "vertex_indices" && p.name != "vertex_index" {
+				if err := skipPLYList(r, p); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			n, err := readPLYInteger(r, p.countType)
+			if err != nil {
+				return nil, err
+			}
+
+			polygon = polygon[:0]
+			for k := 0; k < n; k++ {
+				v, err := readPLYInteger(r, p.valueType)
+				if err != nil {
+					return nil, err
+				}
+				if v >= vertexCount {
+					return nil, fmt.Errorf("index %v out of range", v)
+				}
+				polygon = append(polygon, int32(v))
+			}
+
+			for k := 1; k+1 < len(polygon); k++ {
+				indices = append(indices, polygon[0], polygon[k], polygon[k+1])
+			}
+		}
+	}
+
+	return indices, nil
+}
+
+// skipPLYElement reads (and ignores) all the values of an element
+func skipPLYElement(r plyReader, e *plyElement) error {
+	for i := 0; i < e.count; i++ {
+		for _, p := range e.properties {
+			if p.countType != "" {
+				if err := skipPLYList(r, p); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if _, err := r.read(p.valueType); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// skipPLYList reads (and ignores) a list property
+func skipPLYList(r plyReader, p plyProperty) error {
+	n, err := readPLYInteger(r, p.countType)
+	if err != nil {
+		return err
+	}
+
+	for k := 0; k < n; k++ {
+		if _, err := r.read(p.valueType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readPLYInteger reads a list count or a vertex index, which must be a non-negative integer (the float types are
+// allowed but must hold integral values)
+func readPLYInteger(r plyReader, valueType string) (int, error) {
+	v, err := r.read(valueType)
+	if err != nil {
+		return 0, err
+	}
+	if !(v >= 0 && v <= math.MaxInt32) || v != math.Trunc(v) {
+		return 0, fmt.Errorf("invalid count or index %v", v)
+	}
+
+	return int(v), nil
+}
+
+// plyColorComponent converts a color component into [0, 1] (integer components use the whole range of the type,
+// the negative values of the signed types are clamped to 0)
+func plyColorComponent(v float64, valueType string) float64 {
+	switch valueType {
+	case "uchar", "uint8":
+		return v / math.MaxUint8
+	case "char", "int8":
+		return math.Max(0, v/math.MaxInt8)
+	case "ushort", "uint16":
+		return v / math.MaxUint16
+	case "short", "int16":
+		return math.Max(0, v/math.MaxInt16)
+	case "uint", "uint32":
+		return v / math.MaxUint32
+	case "int", "int32":
+		return math.Max(0, v/math.MaxInt32)
+	}
+
+	return v
+}
+
+// plyTypeSizes gives the size in bytes of the PLY types
+var plyTypeSizes = map[string]int{
+	"char": 1, "int8": 1, "uchar": 1, "uint8": 1,
+	"short": 2, "int16": 2, "ushort": 2, "uint16": 2,
+	"int": 4, "int32": 4, "uint": 4, "uint32": 4,
+	"float": 4, "float32": 4, "double": 8, "float64": 8,
+}
+
+// plyASCIIReader reads the values of an ascii PLY file (separated by white spaces)
+type plyASCIIReader struct {
+	scanner *bufio.Scanner
+}
+
+func (r *plyASCIIReader) read(valueType string) (float64, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return 0, err
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	return strconv.ParseFloat(r.scanner.Text(), 64)
+}
+
+// plyBinaryReader reads the values of a binary PLY file
+type plyBinaryReader struct {
+	r     io.Reader
+	order binary.ByteOrder
+	buf   [8]byte
+}
+
+func (r *plyBinaryReader) read(valueType string) (float64, error) {
+	b := r.buf[:plyTypeSizes[valueType]]
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return 0, err
+	}
+
+	switch valueType {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(r.order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(r.order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(r.order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(r.order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(r.order.Uint32(b))), nil
+	case "double", "float64":
+		return math.Float64frombits(r.order.Uint64(b)), nil
+	}
+
+	return 0, fmt.Errorf("unknown type %v", valueType)
+}
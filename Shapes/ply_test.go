@@ -0,0 +1,186 @@
+package shapes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const asciiPLY = `ply
+format ascii 1.0
+comment a unit square made of a quad
+element vertex 4
+property float x
+property float y
+property float z
+property float nx
+property float ny
+property float nz
+property uchar red
+property uchar green
+property uchar blue
+element face 1
+property list uchar int vertex_indices
+property uchar flags
+end_header
+0 0 0 0 0 1 255 0 0
+1 0 0 0 0 1 0 255 0
+1 1 0 0 0 1 0 0 255
+0 1 0 0 0 1 255 255 255
+4 0 1 2 3 7
+`
+
+// binaryPLY encodes a triangle in the binary format, with signed short colors and a texture coordinate
+func binaryPLY(order binary.ByteOrder, format string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("ply\nformat " + format + " 1.0\n" +
+		"element vertex 3\nproperty double x\nproperty double y\nproperty double z\n" +
+		"property float u\nproperty float v\n" +
+		"property short red\nproperty short green\nproperty short blue\n" +
+		"element edge 1\nproperty int vertex1\nproperty int vertex2\n" +
+		"element face 1\nproperty list uchar uint vertex_index\nend_header\n")
+
+	vertices := []struct {
+		X, Y, Z float64
+		U, V    float32
+		R, G, B int16
+	}{
+		{0, 0, 0, 0, 0, math.MaxInt16, 0, -5},
+		{2, 0, 0, 1, 0, 0, math.MaxInt16, 0},
+		{0, 2, 0, 0, 1, 0, 0, math.MaxInt16},
+	}
+	for _, v := range vertices {
+		_ = binary.Write(&buf, order, v)
+	}
+	_ = binary.Write(&buf, order, [2]int32{0, 1})
+	_ = binary.Write(&buf, order, uint8(3))
+	_ = binary.Write(&buf, order, [3]uint32{0, 1, 2})
+
+	return buf.Bytes()
+}
+
+func writePLY(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "test.ply")
+	if err := ioutil.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadPLYASCII(t *testing.T) {
+	m, err := LoadPLY(writePLY(t, []byte(asciiPLY)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int32{0, 1, 2, 0, 2, 3}; !reflect.DeepEqual(m.Indices, want) {
+		t.Errorf("indices = %v, want %v", m.Indices, want)
+	}
+	if want := []Point3{{}, {X: 1}, {X: 1, Y: 1}, {Y: 1}}; !reflect.DeepEqual(m.Positions, want) {
+		t.Errorf("positions = %v, want %v", m.Positions, want)
+	}
+	if len(m.Normals) != 4 || m.Normals[2] != (Vec3{Z: 1}) {
+		t.Errorf("normals = %v", m.Normals)
+	}
+	if m.UVs != nil {
+		t.Errorf("uvs = %v, want none", m.UVs)
+	}
+	if want := []Color{{R: 1}, {G: 1}, {B: 1}, {R: 1, G: 1, B: 1}}; !reflect.DeepEqual(m.Colors, want) {
+		t.Errorf("colors = %v, want %v", m.Colors, want)
+	}
+	if _, ok := m.Materials[0].(Lambertian); !ok {
+		t.Errorf("material = %T, want a Lambertian using the vertex colors", m.Materials[0])
+	}
+}
+
+func TestLoadPLYBinary(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		order  binary.ByteOrder
+	}{
+		{"binary_little_endian", binary.LittleEndian},
+		{"binary_big_endian", binary.BigEndian},
+	} {
+		t.Run(tt.format, func(t *testing.T) {
+			m, err := LoadPLY(writePLY(t, binaryPLY(tt.order, tt.format)), DefaultMeshMaterial)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := []int32{0, 1, 2}; !reflect.DeepEqual(m.Indices, want) {
+				t.Errorf("indices = %v, want %v", m.Indices, want)
+			}
+			if want := []Point3{{}, {X: 2}, {Y: 2}}; !reflect.DeepEqual(m.Positions, want) {
+				t.Errorf("positions = %v, want %v", m.Positions, want)
+			}
+			if want := []UV{{}, {U: 1}, {V: 1}}; !reflect.DeepEqual(m.UVs, want) {
+				t.Errorf("uvs = %v, want %v", m.UVs, want)
+			}
+			if want := []Color{{R: 1}, {G: 1}, {B: 1}}; !reflect.DeepEqual(m.Colors, want) {
+				t.Errorf("colors = %v, want %v", m.Colors, want)
+			}
+			if m.Materials[0] != DefaultMeshMaterial {
+				t.Errorf("material = %#v, want the given one", m.Materials[0])
+			}
+		})
+	}
+}
+
+func TestLoadPLYErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not a ply file", "obj\n"},
+		{"unknown format", "ply\nformat text 1.0\nend_header\n"},
+		{"unknown type", "ply\nformat ascii 1.0\nelement vertex 1\nproperty real x\nend_header\n"},
+		{"truncated", "ply\nformat ascii 1.0\nelement vertex 2\nproperty float x\nend_header\n1\n"},
+		{"index out of range", "ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\n" +
+			"element face 1\nproperty list uchar int vertex_indices\nend_header\n0\n3 0 1 2\n"},
+		{"negative element count", "ply\nformat ascii 1.0\nelement vertex -1\nproperty float x\nend_header\n"},
+		{"negative list count", "ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\n" +
+			"element face 1\nproperty list int int vertex_indices\nend_header\n0\n0\n0\n-3 0 1 2\n"},
+		{"non-integral index", "ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\n" +
+			"element face 1\nproperty list uchar float vertex_indices\nend_header\n0\n0\n0\n3 0 0.5 2\n"},
+		{"NaN index", "ply\nformat ascii 1.0\nelement vertex 3\nproperty float x\n" +
+			"element face 1\nproperty list uchar float vertex_indices\nend_header\n0\n0\n0\n3 0 NaN 2\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadPLY(writePLY(t, []byte(tt.data)), nil); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestPLYColorComponent(t *testing.T) {
+	tests := []struct {
+		v         float64
+		valueType string
+		want      float64
+	}{
+		{255, "uchar", 1},
+		{127, "char", 1},
+		{-128, "int8", 0},
+		{65535, "ushort", 1},
+		{32767, "short", 1},
+		{-1, "int16", 0},
+		{math.MaxUint32, "uint", 1},
+		{math.MaxInt32, "int32", 1},
+		{math.MinInt32, "int", 0},
+		{0.5, "float", 0.5},
+	}
+
+	for _, tt := range tests {
+		if got := plyColorComponent(tt.v, tt.valueType); got != tt.want {
+			t.Errorf("plyColorComponent(%v, %v) = %v, want %v", tt.v, tt.valueType, got, tt.want)
+		}
+	}
+}
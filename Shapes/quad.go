@@ -0,0 +1,50 @@
+package shapes
+
+import (
+	"math"
+)
+
+// Quad is a parallelogram with a corner at Q and the edges U and V going from it. It can have any orientation.
+// The normal is Cross(U, V) (so the order of the edges decides which side is the front) and u, v go from 0 to 1
+// along U and V.
+type Quad struct {
+	Q        Point3
+	U, V     Vec3
+	Material Material
+}
+
+func (q Quad) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	n := Cross(q.U, q.V)
+	nn := DotProduct(n, n)
+	denom := DotProduct(n, r.Dir)
+	if nn == 0 || denom == 0 {
+		return false, nil
+	}
+
+	t := DotProduct(q.Q.Sub(r.Origin), n) / denom
+	if t < tMin || t > tMax {
+		return false, nil
+	}
+
+	// coordinates of the hit point along the edges
+	p := r.PointAt(t)
+	d := p.Sub(q.Q)
+	alpha := DotProduct(n, Cross(d, q.V)) / nn
+	beta := DotProduct(n, Cross(q.U, d)) / nn
+	if alpha < 0 || alpha > 1 || beta < 0 || beta > 1 {
+		return false, nil
+	}
+
+	hr := HitRecord{T: t, P: p, Normal: n.Scale(1 / math.Sqrt(nn)), Mat: q.Material, U: alpha, V: beta}
+	return true, &hr
+}
+
+func (q Quad) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	box := pointsAABB(q.Q, q.Q.Translate(q.U), q.Q.Translate(q.V), q.Q.Translate(q.U).Translate(q.V))
+	return true, &box
+}
+
+// Area returns the area of the quad
+func (q Quad) Area() float64 {
+	return Cross(q.U, q.V).Length()
+}
@@ -0,0 +1,277 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+)
+
+// The Cylinder, Cone and Torus are defined around a vertical axis (Y) going through Center: use a Transform to
+// orient them differently.
+// Sweep (in degrees) limits the shape to the angles [0, Sweep] around the axis, starting from +X and turning
+// towards +Z (0 means a full turn). u goes around the axis from 0 to 1 over the sweep.
+
+// Cylinder is a cylinder of radius R going from Center up to Center + Height. Capped closes it with 2 disks.
+// On the side v goes from 0 (bottom) to 1 (top), on the caps it goes from 0 (center) to 1 (edge).
+type Cylinder struct {
+	Center   Point3
+	R        float64
+	Height   float64
+	Capped   bool
+	Sweep    float64
+	Material Material
+}
+
+func (cy Cylinder) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	o := r.Origin.Sub(cy.Center)
+	d := r.Dir
+	sweep := sweepAngle(cy.Sweep)
+
+	var candidates []surfaceHit
+	for _, t := range solveQuadratic(d.X*d.X+d.Z*d.Z, 2*(o.X*d.X+o.Z*d.Z), o.X*o.X+o.Z*o.Z-cy.R*cy.R) {
+		candidates = append(candidates, surfaceHit{t: t, surface: sideSurface})
+	}
+	if cy.Capped && d.Y != 0 {
+		candidates = append(candidates, surfaceHit{t: -o.Y / d.Y, surface: bottomSurface}, surfaceHit{t: (cy.Height - o.Y) / d.Y, surface: topSurface})
+	}
+	sortSurfaceHits(candidates)
+
+	for _, c := range candidates {
+		if c.t <= tMin || c.t >= tMax {
+			continue
+		}
+
+		p := o.Add(d.Scale(c.t))
+		phi := azimuth(p)
+		rho := math.Sqrt(p.X*p.X + p.Z*p.Z)
+		if phi > sweep {
+			continue
+		}
+
+		hr := HitRecord{T: c.t, P: r.PointAt(c.t), Mat: cy.Material, U: phi / sweep, V: rho / cy.R}
+		switch c.surface {
+		case sideSurface:
+			if p.Y < 0 || p.Y > cy.Height {
+				continue
+			}
+			hr.Normal = Vec3{X: p.X / cy.R, Z: p.Z / cy.R}
+			hr.V = p.Y / cy.Height
+		case bottomSurface, topSurface:
+			if rho > cy.R {
+				continue
+			}
+			hr.Normal = Vec3{Y: 1}
+			if c.surface == bottomSurface {
+				hr.Normal = Vec3{Y: -1}
+			}
+		}
+
+		return true, &hr
+	}
+
+	return false, nil
+}
+
+func (cy Cylinder) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	c := cy.Center.Vec3()
+	box := AABB{
+		Min: c.Add(Vec3{X: -cy.R, Z: -cy.R}),
+		Max: c.Add(Vec3{X: cy.R, Y: cy.Height, Z: cy.R}),
+	}.padded()
+
+	return true, &box
+}
+
+// Cone is a cone with a base of radius R centered on Center and its apex at Center + Height. Capped closes it
+// with a disk at the base.
+// On the side v goes from 0 (base) to 1 (apex), on the cap it goes from 0 (center) to 1 (edge).
+type Cone struct {
+	Center   Point3
+	R        float64
+	Height   float64
+	Capped   bool
+	Sweep    float64
+	Material Material
+}
+
+func (co Cone) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	o := r.Origin.Sub(co.Center)
+	d := r.Dir
+	sweep := sweepAngle(co.Sweep)
+
+	// x^2 + z^2 = k * (Height - y)^2
+	k := (co.R / co.Height) * (co.R / co.Height)
+	h := co.Height - o.Y
+
+	var candidates []surfaceHit
+	for _, t := range solveQuadratic(d.X*d.X+d.Z*d.Z-k*d.Y*d.Y, 2*(o.X*d.X+o.Z*d.Z+k*d.Y*h), o.X*o.X+o.Z*o.Z-k*h*h) {
+		candidates = append(candidates, surfaceHit{t: t, surface: sideSurface})
+	}
+	if co.Capped && d.Y != 0 {
+		candidates = append(candidates, surfaceHit{t: -o.Y / d.Y, surface: bottomSurface})
+	}
+	sortSurfaceHits(candidates)
+
+	for _, c := range candidates {
+		if c.t <= tMin || c.t >= tMax {
+			continue
+		}
+
+		p := o.Add(d.Scale(c.t))
+		phi := azimuth(p)
+		rho := math.Sqrt(p.X*p.X + p.Z*p.Z)
+		if phi > sweep {
+			continue
+		}
+
+		hr := HitRecord{T: c.t, P: r.PointAt(c.t), Mat: co.Material, U: phi / sweep}
+		if c.surface == sideSurface {
+			// the equation also describes the upside down cone above the apex
+			if p.Y < 0 || p.Y > co.Height {
+				continue
+			}
+			hr.Normal = Vec3{X: p.X, Y: k * (co.Height - p.Y), Z: p.Z}.Unit()
+			hr.V = p.Y / co.Height
+		} else {
+			if rho > co.R {
+				continue
+			}
+			hr.Normal = Vec3{Y: -1}
+			hr.V = rho / co.R
+		}
+
+		return true, &hr
+	}
+
+	return false, nil
+}
+
+func (co Cone) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	c := co.Center.Vec3()
+	box := AABB{
+		Min: c.Add(Vec3{X: -co.R, Z: -co.R}),
+		Max: c.Add(Vec3{X: co.R, Y: co.Height, Z: co.R}),
+	}.padded()
+
+	return true, &box
+}
+
+// Torus is a ring lying in the horizontal plane of Center: the circle of radius R around the axis is the center
+// of a tube of radius Tube.
+// v goes around the tube from 0 to 1, starting from the outside of the ring and turning towards the top.
+type Torus struct {
+	Center   Point3
+	R        float64
+	Tube     float64
+	Sweep    float64
+	Material Material
+}
+
+func (to Torus) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	// the quartic is solved with a normalized direction and an origin moved close to the torus (the closest
+	// point to the center along the ray), which keeps its coefficients in a reasonable range
+	l := r.Dir.Length()
+	d := r.Dir.Scale(1 / l)
+	o := r.Origin.Sub(to.Center)
+	shift := -DotProduct(o, d)
+	o = o.Add(d.Scale(shift))
+
+	// (|p|^2 + R^2 - Tube^2)^2 = 4 * R^2 * (x^2 + z^2) with p = o + s*d
+	R2 := to.R * to.R
+	e := DotProduct(o, o) + R2 - to.Tube*to.Tube
+	f := DotProduct(o, d)
+	roots := solveQuartic(
+		4*f,
+		4*f*f+2*e-4*R2*(d.X*d.X+d.Z*d.Z),
+		4*f*e-8*R2*(o.X*d.X+o.Z*d.Z),
+		e*e-4*R2*(o.X*o.X+o.Z*o.Z),
+	)
+
+	sweep := sweepAngle(to.Sweep)
+	for _, s := range roots {
+		t := (s + shift) / l
+		if t <= tMin || t >= tMax {
+			continue
+		}
+
+		p := o.Add(d.Scale(s))
+		phi := azimuth(p)
+		if phi > sweep {
+			continue
+		}
+
+		// the normal goes from the center of the tube to the point
+		rho := math.Sqrt(p.X*p.X + p.Z*p.Z)
+		n := Vec3{X: p.X - to.R*p.X/rho, Y: p.Y, Z: p.Z - to.R*p.Z/rho}.Unit()
+
+		theta := math.Atan2(n.Y, rho-to.R)
+		if theta < 0 {
+			theta += 2 * math.Pi
+		}
+
+		hr := HitRecord{T: t, P: r.PointAt(t), Normal: n, Mat: to.Material, U: phi / sweep, V: theta / (2 * math.Pi)}
+		return true, &hr
+	}
+
+	return false, nil
+}
+
+func (to Torus) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	c := to.Center.Vec3()
+	e := Vec3{X: to.R + to.Tube, Y: to.Tube, Z: to.R + to.Tube}
+	box := AABB{Min: c.Sub(e), Max: c.Add(e)}
+	return true, &box
+}
+
+// the surfaces of the quadrics
+const (
+	sideSurface = iota
+	bottomSurface
+	topSurface
+)
+
+// surfaceHit is a candidate intersection of a ray with one of the surfaces of a shape
+type surfaceHit struct {
+	t       float64
+	surface int
+}
+
+// sortSurfaceHits sorts the candidates by increasing distance
+func sortSurfaceHits(hits []surfaceHit) {
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].t < hits[j].t
+	})
+}
+
+// sweepAngle converts a sweep in degrees into radians (0 and anything above a full turn give a full turn)
+func sweepAngle(sweep float64) float64 {
+	if sweep <= 0 || sweep >= 360 {
+		return 2 * math.Pi
+	}
+
+	return sweep * math.Pi / 180
+}
+
+// azimuth returns the angle in [0, 2 Pi[ of p around the Y axis, starting from +X and turning towards +Z
+func azimuth(p Vec3) float64 {
+	phi := math.Atan2(p.Z, p.X)
+	if phi < 0 {
+		phi += 2 * math.Pi
+	}
+
+	return phi
+}
+
+// Spans is meaningful for a closed cylinder only (Capped and no Sweep, see NewCSG)
+func (cy Cylinder) Spans(r *Ray) []Span {
+	return surfaceSpans(cy, r)
+}
+
+// Spans is meaningful for a closed cone only (Capped and no Sweep, see NewCSG)
+func (co Cone) Spans(r *Ray) []Span {
+	return surfaceSpans(co, r)
+}
+
+// Spans is meaningful for a closed torus only (no Sweep, see NewCSG)
+func (to Torus) Spans(r *Ray) []Span {
+	return surfaceSpans(to, r)
+}
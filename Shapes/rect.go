@@ -0,0 +1,120 @@
+package shapes
+
+// rectPadding is the thickness given to the bounding box of a rectangle along its normal axis
+// (an AABB with a zero width would never be hit)
+const rectPadding = 0.0001
+
+// XYRect is a rectangle in the plane z = K, bounded by [X0, X1] x [Y0, Y1]. Its normal points towards +Z.
+type XYRect struct {
+	X0, X1, Y0, Y1, K float64
+	Material          Material
+}
+
+func (xy XYRect) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return hitAxisRect(r, tMin, tMax, 0, 1, 2, xy.X0, xy.X1, xy.Y0, xy.Y1, xy.K, xy.Material)
+}
+
+func (xy XYRect) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	return true, &AABB{
+		Min: Vec3{X: xy.X0, Y: xy.Y0, Z: xy.K - rectPadding},
+		Max: Vec3{X: xy.X1, Y: xy.Y1, Z: xy.K + rectPadding},
+	}
+}
+
+// XZRect is a rectangle in the plane y = K, bounded by [X0, X1] x [Z0, Z1]. Its normal points towards +Y.
+type XZRect struct {
+	X0, X1, Z0, Z1, K float64
+	Material          Material
+}
+
+func (xz XZRect) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return hitAxisRect(r, tMin, tMax, 0, 2, 1, xz.X0, xz.X1, xz.Z0, xz.Z1, xz.K, xz.Material)
+}
+
+func (xz XZRect) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	return true, &AABB{
+		Min: Vec3{X: xz.X0, Y: xz.K - rectPadding, Z: xz.Z0},
+		Max: Vec3{X: xz.X1, Y: xz.K + rectPadding, Z: xz.Z1},
+	}
+}
+
+// YZRect is a rectangle in the plane x = K, bounded by [Y0, Y1] x [Z0, Z1]. Its normal points towards +X.
+type YZRect struct {
+	Y0, Y1, Z0, Z1, K float64
+	Material          Material
+}
+
+func (yz YZRect) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return hitAxisRect(r, tMin, tMax, 1, 2, 0, yz.Y0, yz.Y1, yz.Z0, yz.Z1, yz.K, yz.Material)
+}
+
+func (yz YZRect) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	return true, &AABB{
+		Min: Vec3{X: yz.K - rectPadding, Y: yz.Y0, Z: yz.Z0},
+		Max: Vec3{X: yz.K + rectPadding, Y: yz.Y1, Z: yz.Z1},
+	}
+}
+
+// hitAxisRect intersects the ray with the rectangle [a0, a1] x [b0, b1] lying in the plane (axis n) = k.
+// a and b are the in-plane axes (0: X, 1: Y, 2: Z), n is the axis of the normal.
+// u goes along a and v along b, both in [0, 1].
+func hitAxisRect(r *Ray, tMin, tMax float64, a, b, n int, a0, a1, b0, b1, k float64, m Material) (bool, *HitRecord) {
+	dn := r.Dir.GetAxis(n)
+	if dn == 0 {
+		return false, nil
+	}
+
+	origin := r.Origin.Vec3()
+	t := (k - origin.GetAxis(n)) / dn
+	if t < tMin || t > tMax {
+		return false, nil
+	}
+
+	x := origin.GetAxis(a) + t*r.Dir.GetAxis(a)
+	y := origin.GetAxis(b) + t*r.Dir.GetAxis(b)
+	if x < a0 || x > a1 || y < b0 || y > b1 {
+		return false, nil
+	}
+
+	hr := HitRecord{
+		T:      t,
+		P:      r.PointAt(t),
+		Normal: axisVec3(n),
+		Mat:    m,
+		U:      (x - a0) / (a1 - a0),
+		V:      (y - b0) / (b1 - b0),
+	}
+
+	return true, &hr
+}
+
+// axisVec3 returns the unit vector of the axis a (0: X, 1: Y, 2: Z)
+func axisVec3(a int) Vec3 {
+	switch a {
+	case 0:
+		return Vec3{X: 1}
+	case 1:
+		return Vec3{Y: 1}
+	}
+
+	return Vec3{Z: 1}
+}
+
+// FlipFace wraps a HitTable and reverses its normals, which turns for example the walls of a room inward
+type FlipFace struct {
+	HitTable HitTable
+}
+
+func (f FlipFace) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	hit, hr := f.HitTable.Hit(r, tMin, tMax)
+	if !hit {
+		return false, nil
+	}
+
+	hr.Normal = hr.Normal.Negate()
+	return true, hr
+}
+
+func (f FlipFace) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	return f.HitTable.BoundingBox(tm0, tm1)
+}
@@ -0,0 +1,158 @@
+package shapes
+
+import (
+	"math"
+)
+
+const (
+	// sdfMaxSteps is the maximum number of steps of the sphere tracing before giving up
+	sdfMaxSteps = 512
+	// sdfEpsilon is the distance to the surface under which it is considered hit
+	sdfEpsilon = 1e-5
+	// sdfNormalDelta is the offset used to compute the gradient of the distance by central differences
+	sdfNormalDelta = 1e-5
+)
+
+// DistanceFunc returns the signed distance from p to a surface (negative inside, positive outside). It may
+// underestimate the distance but must never overestimate it.
+type DistanceFunc func(p Point3) float64
+
+// SDF is a surface defined by a signed distance function, intersected by sphere tracing: the ray moves forward
+// by the distance to the surface (which is guaranteed to be empty) until it gets close enough to it.
+// Bounds must contain the whole surface and limits the tracing. The normal is the gradient of the distance.
+type SDF struct {
+	Distance DistanceFunc
+	Bounds   AABB
+	Material Material
+
+	// StepScale (in ]0, 1], 0 means 1) shortens the steps for the distance functions which overestimate the
+	// distance (e.g. SDFTwist)
+	StepScale float64
+}
+
+func (s SDF) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	hit, t0, t1 := s.Bounds.clip(r, tMin, tMax)
+	if !hit {
+		return false, nil
+	}
+
+	scale := s.StepScale
+	if scale <= 0 || scale > 1 {
+		scale = 1
+	}
+
+	// the distances are in world units while t is in units of the ray direction
+	invLength := 1 / r.Dir.Length()
+
+	// the ray may start inside (e.g. refracted rays): it then looks for the surface from the inside
+	t := t0
+	side := 1.0
+	if s.Distance(r.PointAt(t)) < 0 {
+		side = -1
+	}
+
+	for i := 0; i < sdfMaxSteps && t <= t1; i++ {
+		d := side * s.Distance(r.PointAt(t))
+		if d < sdfEpsilon {
+			// a ray leaving the surface (scattered) starts on it: move it away
+			if i == 0 && t <= tMin {
+				t += 2 * sdfEpsilon * invLength
+				continue
+			}
+
+			p := r.PointAt(t)
+			hr := HitRecord{T: t, P: p, Normal: s.normal(p), Mat: s.Material}
+			hr.U, hr.V = UVCoordinates(hr.Normal)
+			return true, &hr
+		}
+
+		t += d * scale * invLength
+	}
+
+	return false, nil
+}
+
+// normal returns the normalized gradient of the distance at p
+func (s SDF) normal(p Point3) Vec3 {
+	dx := Vec3{X: sdfNormalDelta}
+	dy := Vec3{Y: sdfNormalDelta}
+	dz := Vec3{Z: sdfNormalDelta}
+
+	return Vec3{
+		X: s.Distance(p.Translate(dx)) - s.Distance(p.Translate(dx.Negate())),
+		Y: s.Distance(p.Translate(dy)) - s.Distance(p.Translate(dy.Negate())),
+		Z: s.Distance(p.Translate(dz)) - s.Distance(p.Translate(dz.Negate())),
+	}.Unit()
+}
+
+func (s SDF) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	box := s.Bounds
+	return true, &box
+}
+
+// SDFSphere is the distance to the sphere of radius r centered on c
+func SDFSphere(c Point3, r float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return p.Sub(c).Length() - r
+	}
+}
+
+// SDFBox is the distance to the box centered on c, of size 2 * halfSize
+func SDFBox(c Point3, halfSize Vec3) DistanceFunc {
+	return func(p Point3) float64 {
+		d := p.Sub(c)
+		q := Vec3{X: math.Abs(d.X) - halfSize.X, Y: math.Abs(d.Y) - halfSize.Y, Z: math.Abs(d.Z) - halfSize.Z}
+		outside := Vec3{X: math.Max(q.X, 0), Y: math.Max(q.Y, 0), Z: math.Max(q.Z, 0)}.Length()
+		inside := math.Min(math.Max(q.X, math.Max(q.Y, q.Z)), 0)
+		return outside + inside
+	}
+}
+
+// SDFTorus is the distance to the torus centered on c lying in the horizontal plane (see Torus)
+func SDFTorus(c Point3, R, tube float64) DistanceFunc {
+	return func(p Point3) float64 {
+		d := p.Sub(c)
+		q := math.Sqrt(d.X*d.X+d.Z*d.Z) - R
+		return math.Sqrt(q*q+d.Y*d.Y) - tube
+	}
+}
+
+// SDFSmoothUnion merges the 2 surfaces with a smooth blend of size k where they meet
+func SDFSmoothUnion(a, b DistanceFunc, k float64) DistanceFunc {
+	return func(p Point3) float64 {
+		da, db := a(p), b(p)
+		h := math.Max(0, math.Min(1, 0.5+0.5*(db-da)/k))
+		return db + (da-db)*h - k*h*(1-h)
+	}
+}
+
+// SDFRound rounds the edges of the surface by inflating it by r
+func SDFRound(f DistanceFunc, r float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return f(p) - r
+	}
+}
+
+// SDFRepeat repeats the surface (centered on the origin) infinitely with the given period along each axis (a 0
+// period does not repeat along that axis). The bounds of the SDF limit the number of copies.
+func SDFRepeat(f DistanceFunc, period Vec3) DistanceFunc {
+	repeat := func(x, period float64) float64 {
+		if period == 0 {
+			return x
+		}
+		return x - period*math.Round(x/period)
+	}
+
+	return func(p Point3) float64 {
+		return f(Point3{X: repeat(p.X, period.X), Y: repeat(p.Y, period.Y), Z: repeat(p.Z, period.Z)})
+	}
+}
+
+// SDFTwist twists the surface around the Y axis by k radians per unit of height. The twist overestimates the
+// distance: use a StepScale lower than 1 (the larger k, the lower).
+func SDFTwist(f DistanceFunc, k float64) DistanceFunc {
+	return func(p Point3) float64 {
+		s, c := math.Sincos(k * p.Y)
+		return f(Point3{X: c*p.X - s*p.Z, Y: p.Y, Z: s*p.X + c*p.Z})
+	}
+}
@@ -0,0 +1,136 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+)
+
+// solveQuadratic returns the real roots (in increasing order) of a*x^2 + b*x + c = 0.
+// It avoids the cancellation of the textbook formula when b*b is much larger than 4*a*c.
+func solveQuadratic(a, b, c float64) []float64 {
+	if a == 0 {
+		if b == 0 {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+
+	d := b*b - 4*a*c
+	if d < 0 {
+		return nil
+	}
+
+	if d == 0 {
+		return []float64{-b / (2 * a)}
+	}
+
+	q := -0.5 * (b + math.Copysign(math.Sqrt(d), b))
+	x0, x1 := q/a, c/q
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+
+	return []float64{x0, x1}
+}
+
+// solveCubic returns the real roots (in increasing order) of x^3 + a*x^2 + b*x + c = 0
+func solveCubic(a, b, c float64) []float64 {
+	q := (a*a - 3*b) / 9
+	r := (2*a*a*a - 9*a*b + 27*c) / 54
+
+	var roots []float64
+	switch d := r*r - q*q*q; {
+	case math.Abs(d) <= 1e-12*math.Max(r*r, q*q*q):
+		// a double (or triple) root, which rounding errors would otherwise turn into a pair of complex roots
+		s := math.Cbrt(r)
+		roots = []float64{-2*s - a/3, s - a/3}
+	case d < 0:
+		// 3 real roots
+		theta := math.Acos(r / math.Sqrt(q*q*q))
+		sq := -2 * math.Sqrt(q)
+		roots = []float64{
+			sq*math.Cos(theta/3) - a/3,
+			sq*math.Cos((theta+2*math.Pi)/3) - a/3,
+			sq*math.Cos((theta-2*math.Pi)/3) - a/3,
+		}
+	default:
+		A := -math.Copysign(math.Cbrt(math.Abs(r)+math.Sqrt(r*r-q*q*q)), r)
+		B := 0.0
+		if A != 0 {
+			B = q / A
+		}
+		roots = []float64{A + B - a/3}
+	}
+
+	for i := range roots {
+		roots[i] = polish(roots[i], []float64{1, a, b, c})
+	}
+	sort.Float64s(roots)
+	return roots
+}
+
+// solveQuartic returns the real roots (in increasing order) of x^4 + a*x^3 + b*x^2 + c*x + d = 0.
+// The roots given by the Ferrari method are refined with a few Newton iterations on the original polynomial,
+// which keeps them accurate even when the intermediate computations lose precision.
+func solveQuartic(a, b, c, d float64) []float64 {
+	// depressed quartic y^4 + p*y^2 + q*y + r = 0 with x = y - a/4
+	a2 := a * a
+	p := b - 3*a2/8
+	q := c - a*b/2 + a2*a/8
+	r := d - a*c/4 + a2*b/16 - 3*a2*a2/256
+
+	var ys []float64
+	if math.Abs(q) < 1e-12 {
+		// biquadratic: y^2 is a root of z^2 + p*z + r
+		for _, z := range solveQuadratic(1, p, r) {
+			if z >= 0 {
+				s := math.Sqrt(z)
+				ys = append(ys, -s, s)
+			}
+		}
+	} else {
+		// (y^2 + p/2 + m)^2 = 2m*y^2 - q*y + m^2 + m*p + p^2/4 - r, where m makes the right side a square:
+		// 8m^3 + 8p*m^2 + (2p^2 - 8r)*m - q^2 = 0
+		cubic := solveCubic(p, p*p/4-r, -q*q/8)
+		m := cubic[len(cubic)-1]
+		if m <= 0 {
+			return nil
+		}
+
+		s := math.Sqrt(2 * m)
+		ys = append(ys, solveQuadratic(1, -s, p/2+m+q/(2*s))...)
+		ys = append(ys, solveQuadratic(1, s, p/2+m-q/(2*s))...)
+	}
+
+	coefficients := []float64{1, a, b, c, d}
+	roots := make([]float64, len(ys))
+	for i, y := range ys {
+		roots[i] = polish(y-a/4, coefficients)
+	}
+
+	sort.Float64s(roots)
+	return roots
+}
+
+// polish refines the root x of the polynomial (coefficients by decreasing degree) with Newton iterations
+func polish(x float64, coefficients []float64) float64 {
+	for i := 0; i < 4; i++ {
+		f, df := 0.0, 0.0
+		for _, c := range coefficients {
+			df = df*x + f
+			f = f*x + c
+		}
+
+		if df == 0 {
+			break
+		}
+
+		next := x - f/df
+		if math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		x = next
+	}
+
+	return x
+}
@@ -0,0 +1,136 @@
+package shapes
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+// solverTolerance is the precision expected from the solvers (double roots are only found to about the square
+// root of the float64 precision)
+const solverTolerance = 1e-6
+
+// monic returns the coefficients (by decreasing degree, without the leading 1) of the polynomial having the roots
+func monic(roots ...float64) []float64 {
+	coefficients := []float64{1}
+	for _, r := range roots {
+		next := make([]float64, len(coefficients)+1)
+		for i, c := range coefficients {
+			next[i] += c
+			next[i+1] -= c * r
+		}
+		coefficients = next
+	}
+
+	return coefficients[1:]
+}
+
+// checkRoots checks that every root found is one of the expected roots, and that every expected root is found
+func checkRoots(t *testing.T, got, want []float64) {
+	t.Helper()
+
+	if !sort.Float64sAreSorted(got) {
+		t.Errorf("roots %v are not sorted", got)
+	}
+
+	near := func(x float64, roots []float64) bool {
+		for _, r := range roots {
+			if math.Abs(x-r) <= solverTolerance*math.Max(1, math.Abs(r)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, x := range got {
+		if !near(x, want) {
+			t.Errorf("unexpected root %v in %v, want %v", x, got, want)
+		}
+	}
+	for _, x := range want {
+		if !near(x, got) {
+			t.Errorf("missing root %v in %v", x, got)
+		}
+	}
+}
+
+func TestSolveQuadratic(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b, c float64
+		want    []float64
+	}{
+		{"2 roots", 1, -3, 2, []float64{1, 2}},
+		{"scaled", -2, 6, -4, []float64{1, 2}},
+		{"repeated root", 1, -2, 1, []float64{1}},
+		{"no real root", 1, 0, 1, nil},
+		{"depressed", 1, 0, -4, []float64{-2, 2}},
+		{"zero root", 1, -5, 0, []float64{0, 5}},
+		{"linear", 0, 2, -4, []float64{2}},
+		{"constant", 0, 0, 1, nil},
+		// the textbook formula loses the small root to cancellation
+		{"large b", 1, -1e8, 1, []float64{1e-8, 1e8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkRoots(t, solveQuadratic(tt.a, tt.b, tt.c), tt.want)
+		})
+	}
+}
+
+func TestSolveCubic(t *testing.T) {
+	tests := []struct {
+		name  string
+		roots []float64 // the real roots of the cubic
+		c     []float64 // the coefficients, when the cubic has complex roots
+	}{
+		{name: "3 roots", roots: []float64{-1, 2, 5}},
+		{name: "close roots", roots: []float64{1, 1.001, 3}},
+		{name: "double root", roots: []float64{1, 1, -2}},
+		{name: "double root with offset", roots: []float64{1, 1, 3}},
+		{name: "triple root", roots: []float64{2, 2, 2}},
+		{name: "depressed", roots: []float64{-3, 1, 2}},
+		{name: "1 real root", roots: []float64{1}, c: []float64{-1, 1, -1}}, // (x-1)(x^2+1)
+		{name: "x^3", roots: []float64{0}, c: []float64{0, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.c
+			if c == nil {
+				c = monic(tt.roots...)
+			}
+			checkRoots(t, solveCubic(c[0], c[1], c[2]), tt.roots)
+		})
+	}
+}
+
+func TestSolveQuartic(t *testing.T) {
+	tests := []struct {
+		name  string
+		roots []float64 // the real roots of the quartic
+		c     []float64 // the coefficients, when the quartic has complex roots
+	}{
+		{name: "4 roots", roots: []float64{-2, 0.5, 1, 3}},
+		{name: "double roots", roots: []float64{1, 1, 2, 2}},
+		{name: "double root", roots: []float64{1, 1, -3, 5}},
+		{name: "biquadratic", roots: []float64{-2, -1, 1, 2}},
+		{name: "depressed", roots: []float64{1, 2, 3, -6}},
+		{name: "quadruple root", roots: []float64{1, 1, 1, 1}},
+		{name: "torus like", roots: []float64{-4.5, -3.5, 3.5, 4.5}},
+		{name: "2 real roots", roots: []float64{-1, 2}, c: []float64{-1, -1, -1, -2}}, // (x+1)(x-2)(x^2+1)
+		{name: "no real root", c: []float64{0, 5, 0, 4}},                              // (x^2+1)(x^2+4)
+		{name: "no real root, not biquadratic", c: []float64{2, 6, 2, 5}},             // (x^2+1)(x^2+2x+5)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.c
+			if c == nil {
+				c = monic(tt.roots...)
+			}
+			checkRoots(t, solveQuartic(c[0], c[1], c[2], c[3]), tt.roots)
+		})
+	}
+}
@@ -74,20 +74,26 @@ func UVCoordinates(p Vec3) (float64, float64) {
 }
 
 
+// MovingSphere is a sphere moving linearly from Center0 at the time Tm0 to Center1 at the time Tm1
 type MovingSphere struct {
 	Center0, Center1 Point3
 	R                float64
-	tm0, Tm1         float64
+	Tm0, Tm1         float64
 	Material         Material
 }
 
+// center returns the center of the sphere at the time t
 func (ms MovingSphere) center(t float64) Point3 {
-	tm := (t - ms.tm0) / ms.Tm1
+	if ms.Tm1 == ms.Tm0 {
+		return ms.Center0
+	}
+
+	tm := (t - ms.Tm0) / (ms.Tm1 - ms.Tm0)
 	return ms.Center0.Translate(ms.Center1.Sub(ms.Center0).Scale(tm))
 }
 
 func (ms MovingSphere) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
-	center := ms.center(r.Rnd.Float64())
+	center := ms.center(r.Time)
 	oc := r.Origin.Sub(center)          // O-C
 	a := DotProduct(r.Dir, r.Dir)       // d.d = d2
 	b := DotProduct(oc, r.Dir)          //  (O-C).d
@@ -138,3 +144,21 @@ func (ms MovingSphere) BoundingBox(tm0, tm1 float64) (bool, *AABB){
 	return true, &outBox
 }
 
+// Spans computes the 2 intersections of the line with the sphere
+func (s Sphere) Spans(r *Ray) []Span {
+	oc := r.Origin.Sub(s.Center)
+	roots := solveQuadratic(DotProduct(r.Dir, r.Dir), 2*DotProduct(oc, r.Dir), DotProduct(oc, oc)-s.R*s.R)
+	if len(roots) != 2 {
+		return nil
+	}
+
+	return []Span{{Enter: s.record(r, roots[0]), Exit: s.record(r, roots[1])}}
+}
+
+// record returns the hit record of the point of the sphere at t along the ray
+func (s Sphere) record(r *Ray, t float64) *HitRecord {
+	p := r.PointAt(t)
+	hr := HitRecord{T: t, P: p, Normal: p.Sub(s.Center).Scale(1 / s.R), Mat: s.Material}
+	hr.U, hr.V = UVCoordinates(hr.Normal)
+	return &hr
+}
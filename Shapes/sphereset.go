@@ -0,0 +1,142 @@
+package shapes
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SphereSet is a large set of spheres (particles) stored in flat arrays instead of one HitTable (and one Material)
+// per sphere: the i-th sphere is centered on Centers[i], has the radius Radii[i] and the material
+// Materials[MaterialIndices[i]] (Materials[0] when there is no material index).
+// The spheres are organized in a bounding volume hierarchy so that Hit does not have to test them all.
+type SphereSet struct {
+	Centers         []Point3
+	Radii           []float64
+	Materials       []Material
+	MaterialIndices []int32
+
+	bvh primitiveBVH
+}
+
+// NewSphereSet creates the set of spheres and builds its acceleration structure. materialIndices may be nil.
+func NewSphereSet(centers []Point3, radii []float64, materials []Material, materialIndices []int32) *SphereSet {
+	if len(materials) == 0 {
+		materials = []Material{DefaultMeshMaterial}
+	}
+
+	ss := &SphereSet{Centers: centers, Radii: radii, Materials: materials, MaterialIndices: materialIndices}
+
+	boxes := make([]AABB, len(centers))
+	for i, c := range centers {
+		r := radii[i]
+		boxes[i] = AABB{Min: Vec3{X: c.X - r, Y: c.Y - r, Z: c.Z - r}, Max: Vec3{X: c.X + r, Y: c.Y + r, Z: c.Z + r}}
+	}
+	ss.bvh = newPrimitiveBVH(boxes)
+
+	return ss
+}
+
+// LoadSphereSet reads the particles of a text dump: a line per particle with its center, radius and optionally
+// its material index ("x y z r [m]", separated by spaces or commas). Empty lines and lines starting with # are
+// skipped.
+func LoadSphereSet(path string, materials []Material) (*SphereSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		centers         []Point3
+		radii           []float64
+		materialIndices []int32
+	)
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		values, err := parseFloats(fields[:minInt(4, len(fields))], 4)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+		}
+		centers = append(centers, Point3{X: values[0], Y: values[1], Z: values[2]})
+		radii = append(radii, values[3])
+
+		m := 0
+		if len(fields) > 4 {
+			if m, err = strconv.Atoi(fields[4]); err != nil {
+				return nil, fmt.Errorf("%v:%d: %v", path, line, err)
+			}
+			if m < 0 || m >= maxInt(1, len(materials)) {
+				return nil, fmt.Errorf("%v:%d: material %d out of range", path, line, m)
+			}
+		}
+		materialIndices = append(materialIndices, int32(m))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return NewSphereSet(centers, radii, materials, materialIndices), nil
+}
+
+// Len returns the number of spheres of the set
+func (ss *SphereSet) Len() int {
+	return len(ss.Centers)
+}
+
+func (ss *SphereSet) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	return ss.bvh.hit(ss, r, tMin, tMax)
+}
+
+func (ss *SphereSet) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	ok, box := ss.bvh.bounds()
+	return ok, &box
+}
+
+// hitPrimitive intersects the ray with the i-th sphere
+func (ss *SphereSet) hitPrimitive(i int, r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	center, radius := ss.Centers[i], ss.Radii[i]
+	oc := r.Origin.Sub(center)
+	a := DotProduct(r.Dir, r.Dir)
+	b := DotProduct(oc, r.Dir)
+	c := DotProduct(oc, oc) - radius*radius
+	d := b*b - a*c
+	if d <= 0 {
+		return false, nil
+	}
+
+	sq := math.Sqrt(d)
+	t := (-b - sq) / a
+	if t <= tMin || t >= tMax {
+		t = (-b + sq) / a
+		if t <= tMin || t >= tMax {
+			return false, nil
+		}
+	}
+
+	p := r.PointAt(t)
+	hr := HitRecord{T: t, P: p, Normal: p.Sub(center).Scale(1 / radius), Mat: ss.material(i)}
+	hr.U, hr.V = UVCoordinates(hr.Normal)
+	return true, &hr
+}
+
+// material returns the material of the i-th sphere
+func (ss *SphereSet) material(i int) Material {
+	if len(ss.MaterialIndices) == 0 {
+		return ss.Materials[0]
+	}
+
+	return ss.Materials[ss.MaterialIndices[i]]
+}
@@ -0,0 +1,148 @@
+package shapes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+// stlHeaderSize is the size of the header of a binary STL file (80 bytes of comment + the triangle count)
+const stlHeaderSize = 84
+
+// stlTriangleSize is the size of a triangle in a binary STL file (normal, 3 vertices and attributes)
+const stlTriangleSize = 50
+
+// STLOptions defines how a STL file is turned into a mesh
+type STLOptions struct {
+	// Scale converts the units of the file into scene units (e.g. 0.001 for parts modeled in millimeters rendered
+	// in meters). 0 means 1.
+	Scale float64
+
+	// SmoothingAngle (in degrees) generates smooth normals between the adjacent triangles making an angle lower
+	// than it. 0 keeps the triangles flat.
+	SmoothingAngle float64
+}
+
+// LoadSTL reads the STL file (ascii or binary) at path and returns its mesh, using the material m for all the
+// triangles (DefaultMeshMaterial when nil). The identical vertices of adjacent triangles are merged.
+func LoadSTL(path string, m Material, options STLOptions) (*Mesh, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var triangles []Point3
+	if isBinarySTL(data) {
+		triangles, err = parseBinarySTL(data)
+	} else {
+		triangles, err = parseASCIISTL(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	scale := options.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	var (
+		positions []Point3
+		indices   = make([]int32, len(triangles))
+		vertices  = map[Point3]int32{}
+	)
+	for i, p := range triangles {
+		p = Point3{X: p.X * scale, Y: p.Y * scale, Z: p.Z * scale}
+		v, ok := vertices[p]
+		if !ok {
+			v = int32(len(positions))
+			vertices[p] = v
+			positions = append(positions, p)
+		}
+		indices[i] = v
+	}
+
+	var normals []Vec3
+	if options.SmoothingAngle > 0 {
+		positions, normals, indices = smoothNormals(positions, indices, options.SmoothingAngle)
+	}
+
+	if m == nil {
+		m = DefaultMeshMaterial
+	}
+
+	return NewMesh(positions, normals, nil, indices, []Material{m}, nil), nil
+}
+
+// isBinarySTL tells whether the data is a binary STL. Some binary files start with "solid" like the ascii ones,
+// so the size announced by the header is checked first.
+func isBinarySTL(data []byte) bool {
+	if len(data) >= stlHeaderSize {
+		count := binary.LittleEndian.Uint32(data[80:stlHeaderSize])
+		if uint64(len(data)) == stlHeaderSize+uint64(count)*stlTriangleSize {
+			return true
+		}
+	}
+
+	return !bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid"))
+}
+
+// parseBinarySTL returns the vertices of the triangles (3 per triangle) of a binary STL
+func parseBinarySTL(data []byte) ([]Point3, error) {
+	if len(data) < stlHeaderSize {
+		return nil, fmt.Errorf("truncated header")
+	}
+
+	count := int(binary.LittleEndian.Uint32(data[80:stlHeaderSize]))
+	if len(data) < stlHeaderSize+count*stlTriangleSize {
+		return nil, fmt.Errorf("truncated file (%d triangles expected)", count)
+	}
+
+	float := func(b []byte) float64 {
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	vertices := make([]Point3, 0, 3*count)
+	for i := 0; i < count; i++ {
+		// skip the normal (recomputed from the vertices)
+		t := data[stlHeaderSize+i*stlTriangleSize+12:]
+		for k := 0; k < 3; k++ {
+			vertices = append(vertices, Point3{X: float(t[12*k:]), Y: float(t[12*k+4:]), Z: float(t[12*k+8:])})
+		}
+	}
+
+	return vertices, nil
+}
+
+// parseASCIISTL returns the vertices of the triangles (3 per triangle) of an ascii STL
+func parseASCIISTL(data []byte) ([]Point3, error) {
+	var vertices []Point3
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "vertex" {
+			continue
+		}
+
+		v, err := parseFloats(fields[1:], 3)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		vertices = append(vertices, Point3{X: v[0], Y: v[1], Z: v[2]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(vertices)%3 != 0 {
+		return nil, fmt.Errorf("%d vertices is not a list of triangles", len(vertices))
+	}
+
+	return vertices, nil
+}
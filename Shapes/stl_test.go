@@ -0,0 +1,173 @@
+package shapes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// binarySTL encodes the triangles (3 vertices each) as a binary STL, with a header starting with "solid" like
+// some exporters write
+func binarySTL(vertices []Point3) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, 80)
+	copy(header, "solid exported as binary")
+	buf.Write(header)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(vertices)/3))
+
+	for i := 0; i < len(vertices); i += 3 {
+		_ = binary.Write(&buf, binary.LittleEndian, [3]float32{})
+		for _, v := range vertices[i : i+3] {
+			_ = binary.Write(&buf, binary.LittleEndian, [3]float32{float32(v.X), float32(v.Y), float32(v.Z)})
+		}
+		_ = binary.Write(&buf, binary.LittleEndian, uint16(0))
+	}
+
+	return buf.Bytes()
+}
+
+const asciiTetrahedron = `solid tetrahedron
+  facet normal 0 0 -1
+    outer loop
+      vertex 0 0 0
+      vertex 0 1 0
+      vertex 1 0 0
+    endloop
+  endfacet
+  facet normal 0 -1 0
+    outer loop
+      vertex 0 0 0
+      vertex 1 0 0
+      vertex 0 0 1
+    endloop
+  endfacet
+  facet normal -1 0 0
+    outer loop
+      vertex 0 0 0
+      vertex 0 0 1
+      vertex 0 1 0
+    endloop
+  endfacet
+  facet normal 1 1 1
+    outer loop
+      vertex 1 0 0
+      vertex 0 1 0
+      vertex 0 0 1
+    endloop
+  endfacet
+endsolid tetrahedron
+`
+
+var tetrahedron = []Point3{
+	{}, {Y: 1}, {X: 1},
+	{}, {X: 1}, {Z: 1},
+	{}, {Z: 1}, {Y: 1},
+	{X: 1}, {Y: 1}, {Z: 1},
+}
+
+func TestParseSTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		binary bool
+		want   []Point3
+		err    bool
+	}{
+		{name: "ascii", data: []byte(asciiTetrahedron), want: tetrahedron},
+		{name: "binary", data: binarySTL(tetrahedron), binary: true, want: tetrahedron},
+		{name: "empty binary", data: binarySTL(nil), binary: true},
+		{name: "truncated binary", data: binarySTL(tetrahedron)[:120], binary: true, err: true},
+		{name: "bad ascii vertex", data: []byte("solid x\nvertex 0 0\nendsolid x\n"), err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.err && isBinarySTL(tt.data) != tt.binary {
+				t.Fatalf("isBinarySTL = %v, want %v", !tt.binary, tt.binary)
+			}
+
+			var got []Point3
+			var err error
+			if tt.binary {
+				got, err = parseBinarySTL(tt.data)
+			} else {
+				got, err = parseASCIISTL(tt.data)
+			}
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("vertices = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSTL(t *testing.T) {
+	dir := t.TempDir()
+	ascii := filepath.Join(dir, "ascii.stl")
+	bin := filepath.Join(dir, "binary.stl")
+	if err := ioutil.WriteFile(ascii, []byte(asciiTetrahedron), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(bin, binarySTL(tetrahedron), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		options   STLOptions
+		positions int
+		normals   int
+		max       float64
+	}{
+		{name: "ascii", path: ascii, positions: 4, max: 1},
+		{name: "binary", path: bin, positions: 4, max: 1},
+		{name: "scaled", path: bin, options: STLOptions{Scale: 0.001}, positions: 4, max: 0.001},
+		// every corner of a tetrahedron is sharp: the vertices are split per face
+		{name: "smoothed", path: ascii, options: STLOptions{SmoothingAngle: 30}, positions: 12, normals: 12, max: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := LoadSTL(tt.path, nil, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.TriangleCount() != 4 {
+				t.Errorf("%d triangles, want 4", m.TriangleCount())
+			}
+			if len(m.Positions) != tt.positions {
+				t.Errorf("%d positions, want %d", len(m.Positions), tt.positions)
+			}
+			if len(m.Normals) != tt.normals {
+				t.Errorf("%d normals, want %d", len(m.Normals), tt.normals)
+			}
+			if m.Materials[0] != DefaultMeshMaterial {
+				t.Errorf("material = %#v, want DefaultMeshMaterial", m.Materials[0])
+			}
+
+			_, box := m.BoundingBox(0, 0)
+			if math.Abs(box.Max.X-tt.max) > 1e-9 {
+				t.Errorf("box = %v, want max X %v", box, tt.max)
+			}
+		})
+	}
+}
+
+func TestLoadSTLMissingFile(t *testing.T) {
+	if _, err := LoadSTL(filepath.Join(t.TempDir(), "missing.stl"), nil, STLOptions{}); err == nil {
+		t.Error("expected an error")
+	}
+}
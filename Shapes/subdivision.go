@@ -0,0 +1,483 @@
+package shapes
+
+import (
+	"math"
+)
+
+// SubdivisionScheme is the way Subdivide refines a mesh
+type SubdivisionScheme int
+
+const (
+	// Loop splits every triangle into 4 triangles
+	Loop SubdivisionScheme = iota
+	// CatmullClark splits every polygon with n sides into n quads
+	CatmullClark
+)
+
+// Crease makes the edge between the vertices A and B of a mesh sharp for the first Sharpness levels of
+// subdivision (rounded up): the surface keeps a crease along it. math.Inf(1) makes it sharp at every level.
+type Crease struct {
+	A, B      int32
+	Sharpness float64
+}
+
+// Subdivide applies levels levels of subdivision to the mesh and returns the refined mesh, which gets smoother
+// with every level. The boundaries of the mesh and the creases stay sharp.
+// The vertices of the mesh sharing a position are merged, so that the seams of the texture coordinates do not
+// crack. The texture coordinates are interpolated linearly, the normals are the average of the normals of the
+// faces around each vertex which are not separated by a sharp edge, and the vertex colors are dropped.
+// CatmullClark rebuilds the quads split into 2 triangles by the loaders: the coplanar triangles (a, b, c) and
+// (a, c, d) following each other are merged back into the quad (a, b, c, d).
+func Subdivide(m *Mesh, scheme SubdivisionScheme, levels int, creases []Crease) *Mesh {
+	sm := newSubdivisionMesh(m, scheme == CatmullClark, creases)
+	for i := 0; i < levels; i++ {
+		if scheme == CatmullClark {
+			sm = sm.catmullClark()
+		} else {
+			sm = sm.loop()
+		}
+	}
+
+	return sm.mesh(m.Materials)
+}
+
+// subdivisionMesh is a polygon mesh whose faces reference the vertices by position (no duplicate)
+type subdivisionMesh struct {
+	positions []Vec3
+	faces     [][]int32
+	// the texture coordinates of the corners of the faces (nil when the mesh has none)
+	uvs       [][]UV
+	materials []int32
+	// the sharpness of the creases
+	sharpness map[[2]int32]float64
+}
+
+// subdivisionEdge is an edge of a subdivisionMesh
+type subdivisionEdge struct {
+	faces     []int32
+	sharpness float64
+}
+
+// sharp tells whether the edge is a crease, a boundary or is shared by more than 2 faces
+func (e *subdivisionEdge) sharp() bool {
+	return e.sharpness > 0 || len(e.faces) != 2
+}
+
+// subdivisionEdgeKey returns the key of the edge between a and b in both directions
+func subdivisionEdgeKey(a, b int32) [2]int32 {
+	if a > b {
+		a, b = b, a
+	}
+
+	return [2]int32{a, b}
+}
+
+// newSubdivisionMesh merges the vertices of the mesh sharing a position and optionally merges its triangles back
+// into quads
+func newSubdivisionMesh(m *Mesh, quads bool, creases []Crease) *subdivisionMesh {
+	sm := &subdivisionMesh{sharpness: map[[2]int32]float64{}}
+
+	ids := map[Point3]int32{}
+	vertexIDs := make([]int32, len(m.Positions))
+	for i, p := range m.Positions {
+		id, ok := ids[p]
+		if !ok {
+			id = int32(len(sm.positions))
+			ids[p] = id
+			sm.positions = append(sm.positions, p.Vec3())
+		}
+		vertexIDs[i] = id
+	}
+
+	material := func(i int) int32 {
+		if len(m.MaterialIndices) == 0 {
+			return 0
+		}
+		return m.MaterialIndices[i]
+	}
+	normal := func(i int) Vec3 {
+		a, b, c := m.vertices(i)
+		return Cross(m.Positions[b].Sub(m.Positions[a]), m.Positions[c].Sub(m.Positions[a])).Unit()
+	}
+
+	for i := 0; i < m.TriangleCount(); i++ {
+		a, b, c := m.vertices(i)
+		face := []int32{a, b, c}
+
+		if quads && i+1 < m.TriangleCount() {
+			a2, c2, d := m.vertices(i + 1)
+			if a2 == a && c2 == c && material(i) == material(i+1) && DotProduct(normal(i), normal(i+1)) > 0.9999 {
+				face = append(face, d)
+			}
+		}
+
+		ids := make([]int32, len(face))
+		for k, v := range face {
+			ids[k] = vertexIDs[v]
+		}
+		sm.faces = append(sm.faces, ids)
+		sm.materials = append(sm.materials, material(i))
+
+		if len(m.UVs) > 0 {
+			uvs := make([]UV, len(face))
+			for k, v := range face {
+				uvs[k] = m.UVs[v]
+			}
+			sm.uvs = append(sm.uvs, uvs)
+		}
+
+		if len(face) == 4 {
+			i++
+		}
+	}
+
+	for _, c := range creases {
+		if c.Sharpness > 0 && c.A >= 0 && c.B >= 0 && int(c.A) < len(vertexIDs) && int(c.B) < len(vertexIDs) {
+			sm.sharpness[subdivisionEdgeKey(vertexIDs[c.A], vertexIDs[c.B])] = c.Sharpness
+		}
+	}
+
+	return sm
+}
+
+// edges returns the edges of the mesh
+func (sm *subdivisionMesh) edges() map[[2]int32]*subdivisionEdge {
+	edges := map[[2]int32]*subdivisionEdge{}
+	for f, face := range sm.faces {
+		for k, a := range face {
+			key := subdivisionEdgeKey(a, face[(k+1)%len(face)])
+			e, ok := edges[key]
+			if !ok {
+				e = &subdivisionEdge{sharpness: sm.sharpness[key]}
+				edges[key] = e
+			}
+			e.faces = append(e.faces, int32(f))
+		}
+	}
+
+	return edges
+}
+
+// vertexNeighbors returns the vertices connected to each vertex by an edge, and those connected by a sharp edge
+func (sm *subdivisionMesh) vertexNeighbors(edges map[[2]int32]*subdivisionEdge) ([][]int32, [][]int32) {
+	neighbors := make([][]int32, len(sm.positions))
+	sharpNeighbors := make([][]int32, len(sm.positions))
+	for key, e := range edges {
+		a, b := key[0], key[1]
+		neighbors[a] = append(neighbors[a], b)
+		neighbors[b] = append(neighbors[b], a)
+		if e.sharp() {
+			sharpNeighbors[a] = append(sharpNeighbors[a], b)
+			sharpNeighbors[b] = append(sharpNeighbors[b], a)
+		}
+	}
+
+	return neighbors, sharpNeighbors
+}
+
+// creaseVertex moves the vertex v according to the sharp edges around it: a vertex on a crease (2 sharp edges)
+// only depends on its neighbors along the crease, and a corner (more than 2 sharp edges) does not move. It tells
+// whether the vertex follows one of these rules.
+func (sm *subdivisionMesh) creaseVertex(v int32, sharpNeighbors []int32) (Vec3, bool) {
+	p := sm.positions[v]
+	switch {
+	case len(sharpNeighbors) == 2:
+		a, b := sm.positions[sharpNeighbors[0]], sm.positions[sharpNeighbors[1]]
+		return p.Scale(0.75).Add(a.Add(b).Scale(0.125)), true
+	case len(sharpNeighbors) > 2:
+		return p, true
+	}
+
+	return Vec3{}, false
+}
+
+// childSharpness makes the halves of the creases one level less sharp
+func (sm *subdivisionMesh) childSharpness(out *subdivisionMesh, a, b, mid int32) {
+	if s := sm.sharpness[subdivisionEdgeKey(a, b)] - 1; s > 0 {
+		out.sharpness[subdivisionEdgeKey(a, mid)] = s
+		out.sharpness[subdivisionEdgeKey(mid, b)] = s
+	}
+}
+
+// midUV returns the texture coordinates halfway between a and b
+func midUV(a, b UV) UV {
+	return UV{U: (a.U + b.U) / 2, V: (a.V + b.V) / 2}
+}
+
+// loop applies a level of Loop subdivision. The faces must be triangles.
+func (sm *subdivisionMesh) loop() *subdivisionMesh {
+	edges := sm.edges()
+	neighbors, sharpNeighbors := sm.vertexNeighbors(edges)
+	out := &subdivisionMesh{sharpness: map[[2]int32]float64{}}
+
+	// the vertices move towards the average of their neighbors
+	out.positions = make([]Vec3, len(sm.positions), len(sm.positions)+len(edges))
+	for v, p := range sm.positions {
+		if q, ok := sm.creaseVertex(int32(v), sharpNeighbors[v]); ok {
+			out.positions[v] = q
+			continue
+		}
+
+		n := float64(len(neighbors[v]))
+		beta := 3.0 / 16
+		if n > 3 {
+			beta = 3 / (8 * n)
+		}
+		q := p.Scale(1 - n*beta)
+		for _, w := range neighbors[v] {
+			q = q.Add(sm.positions[w].Scale(beta))
+		}
+		out.positions[v] = q
+	}
+
+	// a new vertex on every edge
+	edgePoints := make(map[[2]int32]int32, len(edges))
+	for _, face := range sm.faces {
+		for k, a := range face {
+			b := face[(k+1)%3]
+			key := subdivisionEdgeKey(a, b)
+			if _, ok := edgePoints[key]; ok {
+				continue
+			}
+
+			e := edges[key]
+			q := sm.positions[a].Add(sm.positions[b]).Scale(0.5)
+			if !e.sharp() {
+				q = sm.positions[a].Add(sm.positions[b]).Scale(3.0 / 8)
+				for _, f := range e.faces {
+					for _, c := range sm.faces[f] {
+						if c != a && c != b {
+							q = q.Add(sm.positions[c].Scale(1.0 / 8))
+						}
+					}
+				}
+			}
+
+			edgePoints[key] = int32(len(out.positions))
+			out.positions = append(out.positions, q)
+			sm.childSharpness(out, a, b, edgePoints[key])
+		}
+	}
+
+	// every triangle is split into 4
+	out.materials = make([]int32, 0, 4*len(sm.faces))
+	for f, face := range sm.faces {
+		a, b, c := face[0], face[1], face[2]
+		ab, bc, ca := edgePoints[subdivisionEdgeKey(a, b)], edgePoints[subdivisionEdgeKey(b, c)], edgePoints[subdivisionEdgeKey(c, a)]
+		out.faces = append(out.faces, []int32{a, ab, ca}, []int32{ab, b, bc}, []int32{ca, bc, c}, []int32{ab, bc, ca})
+		for k := 0; k < 4; k++ {
+			out.materials = append(out.materials, sm.materials[f])
+		}
+
+		if sm.uvs != nil {
+			uv := sm.uvs[f]
+			uvAB, uvBC, uvCA := midUV(uv[0], uv[1]), midUV(uv[1], uv[2]), midUV(uv[2], uv[0])
+			out.uvs = append(out.uvs, []UV{uv[0], uvAB, uvCA}, []UV{uvAB, uv[1], uvBC}, []UV{uvCA, uvBC, uv[2]}, []UV{uvAB, uvBC, uvCA})
+		}
+	}
+
+	return out
+}
+
+// catmullClark applies a level of Catmull-Clark subdivision
+func (sm *subdivisionMesh) catmullClark() *subdivisionMesh {
+	edges := sm.edges()
+	neighbors, sharpNeighbors := sm.vertexNeighbors(edges)
+	out := &subdivisionMesh{sharpness: map[[2]int32]float64{}}
+
+	// a new vertex at the center of every face
+	facePoints := make([]Vec3, len(sm.faces))
+	vertexFaces := make([][]int32, len(sm.positions))
+	for f, face := range sm.faces {
+		var c Vec3
+		for _, v := range face {
+			c = c.Add(sm.positions[v])
+			vertexFaces[v] = append(vertexFaces[v], int32(f))
+		}
+		facePoints[f] = c.Scale(1 / float64(len(face)))
+	}
+
+	// the vertices move towards the centers of the faces and edges around them
+	out.positions = make([]Vec3, len(sm.positions), len(sm.positions)+len(edges)+len(sm.faces))
+	for v, p := range sm.positions {
+		if q, ok := sm.creaseVertex(int32(v), sharpNeighbors[v]); ok {
+			out.positions[v] = q
+			continue
+		}
+		if len(neighbors[v]) == 0 {
+			out.positions[v] = p
+			continue
+		}
+
+		var faces, mids Vec3
+		for _, f := range vertexFaces[v] {
+			faces = faces.Add(facePoints[f])
+		}
+		for _, w := range neighbors[v] {
+			mids = mids.Add(p.Add(sm.positions[w]).Scale(0.5))
+		}
+		n := float64(len(neighbors[v]))
+		faces = faces.Scale(1 / float64(len(vertexFaces[v])))
+		mids = mids.Scale(1 / n)
+		out.positions[v] = faces.Add(mids.Scale(2)).Add(p.Scale(n - 3)).Scale(1 / n)
+	}
+
+	faceIDs := make([]int32, len(sm.faces))
+	for f := range sm.faces {
+		faceIDs[f] = int32(len(out.positions))
+		out.positions = append(out.positions, facePoints[f])
+	}
+
+	// a new vertex on every edge
+	edgePoints := make(map[[2]int32]int32, len(edges))
+	for _, face := range sm.faces {
+		for k, a := range face {
+			b := face[(k+1)%len(face)]
+			key := subdivisionEdgeKey(a, b)
+			if _, ok := edgePoints[key]; ok {
+				continue
+			}
+
+			e := edges[key]
+			q := sm.positions[a].Add(sm.positions[b]).Scale(0.5)
+			if !e.sharp() {
+				q = sm.positions[a].Add(sm.positions[b]).Add(facePoints[e.faces[0]]).Add(facePoints[e.faces[1]]).Scale(0.25)
+			}
+
+			edgePoints[key] = int32(len(out.positions))
+			out.positions = append(out.positions, q)
+			sm.childSharpness(out, a, b, edgePoints[key])
+		}
+	}
+
+	// every polygon is split into quads around its center
+	for f, face := range sm.faces {
+		n := len(face)
+		var center UV
+		if sm.uvs != nil {
+			for _, uv := range sm.uvs[f] {
+				center.U += uv.U / float64(n)
+				center.V += uv.V / float64(n)
+			}
+		}
+
+		for k, v := range face {
+			next, prev := face[(k+1)%n], face[(k+n-1)%n]
+			out.faces = append(out.faces, []int32{v, edgePoints[subdivisionEdgeKey(v, next)], faceIDs[f], edgePoints[subdivisionEdgeKey(prev, v)]})
+			out.materials = append(out.materials, sm.materials[f])
+
+			if sm.uvs != nil {
+				uv := sm.uvs[f]
+				out.uvs = append(out.uvs, []UV{uv[k], midUV(uv[k], uv[(k+1)%n]), center, midUV(uv[(k+n-1)%n], uv[k])})
+			}
+		}
+	}
+
+	return out
+}
+
+// mesh triangulates the faces into a Mesh
+func (sm *subdivisionMesh) mesh(materials []Material) *Mesh {
+	edges := sm.edges()
+
+	// the corners of the faces around a vertex which are not separated by a sharp edge share their normal
+	cornerIDs := make([][]int32, len(sm.faces))
+	var count int32
+	for f, face := range sm.faces {
+		cornerIDs[f] = make([]int32, len(face))
+		for k := range face {
+			cornerIDs[f][k] = count
+			count++
+		}
+	}
+	parent := make([]int32, count)
+	for i := range parent {
+		parent[i] = int32(i)
+	}
+	var find func(i int32) int32
+	find = func(i int32) int32 {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	corner := func(f int32, v int32) int32 {
+		for k, w := range sm.faces[f] {
+			if w == v {
+				return cornerIDs[f][k]
+			}
+		}
+		return -1
+	}
+	for key, e := range edges {
+		if e.sharp() {
+			continue
+		}
+		for _, v := range key {
+			parent[find(corner(e.faces[0], v))] = find(corner(e.faces[1], v))
+		}
+	}
+
+	// the normals of the faces (Newell's method) weighted by their area
+	normals := make([]Vec3, count)
+	for f, face := range sm.faces {
+		var n Vec3
+		for k, v := range face {
+			a, b := sm.positions[v], sm.positions[face[(k+1)%len(face)]]
+			n = n.Add(Vec3{X: (a.Y - b.Y) * (a.Z + b.Z), Y: (a.Z - b.Z) * (a.X + b.X), Z: (a.X - b.X) * (a.Y + b.Y)})
+		}
+		n = n.Scale(0.5)
+		for k := range face {
+			root := find(cornerIDs[f][k])
+			normals[root] = normals[root].Add(n)
+		}
+	}
+
+	type vertex struct {
+		group int32
+		uv    UV
+	}
+	var (
+		positions       []Point3
+		vertexNormals   []Vec3
+		uvs             []UV
+		indices         []int32
+		materialIndices []int32
+		vertices        = map[vertex]int32{}
+	)
+	for f, face := range sm.faces {
+		ids := make([]int32, len(face))
+		for k, v := range face {
+			key := vertex{group: find(cornerIDs[f][k])}
+			if sm.uvs != nil {
+				key.uv = sm.uvs[f][k]
+			}
+
+			id, ok := vertices[key]
+			if !ok {
+				id = int32(len(positions))
+				vertices[key] = id
+				p := sm.positions[v]
+				positions = append(positions, Point3{X: p.X, Y: p.Y, Z: p.Z})
+				vertexNormals = append(vertexNormals, normals[key.group].Unit())
+				if sm.uvs != nil {
+					uvs = append(uvs, key.uv)
+				}
+			}
+			ids[k] = id
+		}
+
+		for k := 1; k+1 < len(ids); k++ {
+			indices = append(indices, ids[0], ids[k], ids[k+1])
+			materialIndices = append(materialIndices, sm.materials[f])
+		}
+	}
+
+	for i, n := range vertexNormals {
+		if math.IsNaN(n.X) {
+			vertexNormals[i] = Vec3{}
+		}
+	}
+
+	return NewMesh(positions, vertexNormals, uvs, indices, materials, materialIndices)
+}
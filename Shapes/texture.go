@@ -2,6 +2,10 @@ package shapes
 
 import (
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"math"
 	"math/rand"
 	"os"
@@ -12,6 +16,21 @@ type Texture interface {
 	Value(u, v float64, p Point3) Color
 }
 
+// HitTexture is implemented by the textures which need the whole hit record (and not only u, v and p) to
+// compute their color
+type HitTexture interface {
+	HitValue(rec *HitRecord) Color
+}
+
+// textureValue returns the color of the texture at the hit point
+func textureValue(t Texture, rec *HitRecord) Color {
+	if ht, ok := t.(HitTexture); ok {
+		return ht.HitValue(rec)
+	}
+
+	return t.Value(rec.U, rec.V, rec.P)
+}
+
 type SolidColor struct {
 	ColorValue Color
 }
@@ -141,3 +160,66 @@ func (n NoiseTexture) Value(u, v float64, p Point3) Color {
 	black := Color{1, 1, 1}.Scale(t)
 	return black
 }
+
+// ImageTexture maps an image on the surface using the u, v coordinates (repeated outside of [0, 1]).
+// v = 0 is the bottom of the image.
+type ImageTexture struct {
+	Image image.Image
+}
+
+// LoadImageTexture decodes the image file (png, jpeg or gif) at path
+func LoadImageTexture(path string) (ImageTexture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ImageTexture{}, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return ImageTexture{}, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return ImageTexture{Image: img}, nil
+}
+
+func (it ImageTexture) Value(u, v float64, p Point3) Color {
+	// solid cyan makes a missing image obvious
+	if it.Image == nil {
+		return Color{G: 1, B: 1}
+	}
+
+	b := it.Image.Bounds()
+	u = u - math.Floor(u)
+	v = 1 - (v - math.Floor(v))
+
+	x := b.Min.X + int(u*float64(b.Dx()))
+	y := b.Min.Y + int(v*float64(b.Dy()))
+	if x >= b.Max.X {
+		x = b.Max.X - 1
+	}
+	if y >= b.Max.Y {
+		y = b.Max.Y - 1
+	}
+
+	r, g, bl, _ := it.Image.At(x, y).RGBA()
+	return Color{R: float64(r) / 0xFFFF, G: float64(g) / 0xFFFF, B: float64(bl) / 0xFFFF}
+}
+
+// VertexColorTexture uses the colors interpolated from the vertices of a mesh (see Mesh.Colors). Fallback is
+// used on the surfaces without vertex colors.
+type VertexColorTexture struct {
+	Fallback Color
+}
+
+func (vc VertexColorTexture) Value(u, v float64, p Point3) Color {
+	return vc.Fallback
+}
+
+func (vc VertexColorTexture) HitValue(rec *HitRecord) Color {
+	if rec.VertexColor == nil {
+		return vc.Fallback
+	}
+
+	return *rec.VertexColor
+}
@@ -0,0 +1,109 @@
+package shapes
+
+// Transform is an instance of a HitTable placed in the world by an affine transformation. The same HitTable
+// (e.g. a large mesh) can be shared by many instances.
+// The rays are moved into the object space of the HitTable, and the hit point and normal are moved back into
+// world space.
+type Transform struct {
+	HitTable HitTable
+	matrix   Matrix
+	inverse  Matrix
+}
+
+// NewTransform returns the instance of h transformed by m. Transforming a Transform combines both
+// transformations instead of nesting them.
+// A matrix which cannot be inverted gives an instance which is never hit.
+func NewTransform(h HitTable, m Matrix) Transform {
+	if t, ok := h.(Transform); ok {
+		h = t.HitTable
+		m = m.Mult(t.matrix)
+	}
+
+	ok, inverse := m.Inverse()
+	if !ok {
+		return Transform{HitTable: HitTableList{}, matrix: m}
+	}
+
+	return Transform{HitTable: h, matrix: m, inverse: inverse}
+}
+
+// Translate returns the instance of h moved by offset
+func Translate(h HitTable, offset Vec3) Transform {
+	return NewTransform(h, TranslationMatrix(offset))
+}
+
+// RotateX returns the instance of h rotated by angle (in degrees) around the X axis
+func RotateX(h HitTable, angle float64) Transform {
+	return NewTransform(h, RotationXMatrix(angle))
+}
+
+// RotateY returns the instance of h rotated by angle (in degrees) around the Y axis
+func RotateY(h HitTable, angle float64) Transform {
+	return NewTransform(h, RotationYMatrix(angle))
+}
+
+// RotateZ returns the instance of h rotated by angle (in degrees) around the Z axis
+func RotateZ(h HitTable, angle float64) Transform {
+	return NewTransform(h, RotationZMatrix(angle))
+}
+
+// Scale returns the instance of h scaled along each axis by the matching component of factors
+func Scale(h HitTable, factors Vec3) Transform {
+	return NewTransform(h, ScaleMatrix(factors))
+}
+
+// Matrix returns the transformation from object space to world space
+func (t Transform) Matrix() Matrix {
+	return t.matrix
+}
+
+func (t Transform) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	// the direction is not normalized so that t is the same in both spaces
+	or := *r
+	or.Origin = t.inverse.Point(r.Origin)
+	or.Dir = t.inverse.Vec3(r.Dir)
+
+	hit, hr := t.HitTable.Hit(&or, tMin, tMax)
+	if !hit {
+		return false, nil
+	}
+
+	return true, t.toWorld(hr)
+}
+
+// toWorld moves the hit record from object space to world space
+func (t Transform) toWorld(hr *HitRecord) *HitRecord {
+	hr.P = t.matrix.Point(hr.P)
+	hr.Normal = t.inverse.TransposedVec3(hr.Normal).Unit()
+	return hr
+}
+
+func (t Transform) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	ok, box := t.HitTable.BoundingBox(tm0, tm1)
+	if !ok {
+		return false, nil
+	}
+
+	tb := t.matrix.Box(*box)
+	return true, &tb
+}
+
+// Spans transforms the spans of the instantiated HitTable when it is a Solid
+func (t Transform) Spans(r *Ray) []Span {
+	s, ok := t.HitTable.(Solid)
+	if !ok {
+		return surfaceSpans(t, r)
+	}
+
+	or := *r
+	or.Origin = t.inverse.Point(r.Origin)
+	or.Dir = t.inverse.Vec3(r.Dir)
+
+	spans := s.Spans(&or)
+	for i := range spans {
+		spans[i].Enter = t.toWorld(spans[i].Enter)
+		spans[i].Exit = t.toWorld(spans[i].Exit)
+	}
+
+	return spans
+}
@@ -0,0 +1,89 @@
+package shapes
+
+// triangleEpsilon is the threshold under which a ray is considered parallel to a triangle, relative to the lengths
+// of the direction of the ray and of the edges of the triangle (so that it does not depend on their scale)
+const triangleEpsilon = 1e-9
+
+// UV defines texture coordinates
+type UV struct {
+	U, V float64
+}
+
+// Triangle defines a triangle by its 3 vertices (counter clockwise order gives the front face).
+// The per vertex normals N0/N1/N2 and texture coordinates UV0/UV1/UV2 are optional: when left to zero, the
+// geometric normal and the barycentric coordinates are used instead.
+type Triangle struct {
+	V0, V1, V2    Point3
+	N0, N1, N2    Vec3
+	UV0, UV1, UV2 UV
+	Material      Material
+}
+
+// Hit uses the Möller-Trumbore ray/triangle intersection
+func (tr Triangle) Hit(r *Ray, tMin, tMax float64) (bool, *HitRecord) {
+	hit, t, u, v := intersectTriangle(r, tr.V0, tr.V1, tr.V2, tMin, tMax)
+	if !hit {
+		return false, nil
+	}
+
+	hr := HitRecord{
+		T:   t,
+		P:   r.PointAt(t),
+		Mat: tr.Material,
+	}
+
+	w := 1 - u - v
+	if tr.N0 == (Vec3{}) && tr.N1 == (Vec3{}) && tr.N2 == (Vec3{}) {
+		hr.Normal = Cross(tr.V1.Sub(tr.V0), tr.V2.Sub(tr.V0)).Unit()
+	} else {
+		hr.Normal = tr.N0.Scale(w).Add(tr.N1.Scale(u)).Add(tr.N2.Scale(v)).Unit()
+	}
+
+	if tr.UV0 == (UV{}) && tr.UV1 == (UV{}) && tr.UV2 == (UV{}) {
+		hr.U, hr.V = u, v
+	} else {
+		hr.U = w*tr.UV0.U + u*tr.UV1.U + v*tr.UV2.U
+		hr.V = w*tr.UV0.V + u*tr.UV1.V + v*tr.UV2.V
+	}
+
+	return true, &hr
+}
+
+func (tr Triangle) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
+	box := pointsAABB(tr.V0, tr.V1, tr.V2)
+	return true, &box
+}
+
+// intersectTriangle returns the distance t along the ray and the barycentric coordinates (u, v) of the hit
+// point (weights of v1 and v2) when the ray hits the triangle in ]tMin, tMax[
+func intersectTriangle(r *Ray, v0, v1, v2 Point3, tMin, tMax float64) (bool, float64, float64, float64) {
+	e1 := v1.Sub(v0)
+	e2 := v2.Sub(v0)
+
+	p := Cross(r.Dir, e2)
+	det := DotProduct(e1, p)
+	// |det| <= |dir|.|e1|.|e2|, compared squared to avoid the square roots (a degenerate triangle is rejected too)
+	if det*det <= triangleEpsilon*triangleEpsilon*r.Dir.squared()*e1.squared()*e2.squared() {
+		return false, 0, 0, 0
+	}
+
+	invDet := 1 / det
+	s := r.Origin.Sub(v0)
+	u := DotProduct(s, p) * invDet
+	if u < 0 || u > 1 {
+		return false, 0, 0, 0
+	}
+
+	q := Cross(s, e1)
+	v := DotProduct(r.Dir, q) * invDet
+	if v < 0 || u+v > 1 {
+		return false, 0, 0, 0
+	}
+
+	t := DotProduct(e2, q) * invDet
+	if t <= tMin || t >= tMax {
+		return false, 0, 0, 0
+	}
+
+	return true, t, u, v
+}
@@ -0,0 +1,38 @@
+package shapes
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTriangleHitScale(t *testing.T) {
+	for _, scale := range []float64{1, 1e-3, 1e-6, 1e3} {
+		tr := Triangle{V1: Point3{X: scale}, V2: Point3{Y: scale}}
+		for _, dirScale := range []float64{1, 1e-3} {
+			// towards the middle of the triangle, from a distance of 10 triangles
+			r := Ray{Origin: Point3{X: 0.25 * scale, Y: 0.25 * scale, Z: 10 * scale}, Dir: Vec3{Z: -dirScale}}
+			hit, hr := tr.Hit(&r, 0, math.Inf(1))
+			if !hit {
+				t.Errorf("triangle of size %v, direction of length %v: missed", scale, dirScale)
+				continue
+			}
+			if want := 10 * scale / dirScale; math.Abs(hr.T-want) > 1e-9*want {
+				t.Errorf("triangle of size %v, direction of length %v: t = %v, want %v", scale, dirScale, hr.T, want)
+			}
+		}
+	}
+}
+
+func TestTriangleHitParallel(t *testing.T) {
+	tr := Triangle{V1: Point3{X: 1e-3}, V2: Point3{Y: 1e-3}}
+	r := Ray{Origin: Point3{X: -1, Y: 2.5e-4}, Dir: Vec3{X: 1}}
+	if hit, _ := tr.Hit(&r, 0, math.Inf(1)); hit {
+		t.Error("a ray in the plane of the triangle hits it")
+	}
+
+	degenerate := Triangle{V1: Point3{X: 1}, V2: Point3{X: 2}}
+	r = Ray{Origin: Point3{X: 0.5, Z: 1}, Dir: Vec3{Z: -1}}
+	if hit, _ := degenerate.Hit(&r, 0, math.Inf(1)); hit {
+		t.Error("a degenerate triangle is hit")
+	}
+}
@@ -128,11 +128,14 @@ func (p Point3) Vec3() Vec3 {
 	return Vec3{p.X, p.Y, p.Z}
 }
 
-// Ray represents a ray defined by its origin and direction
+// Ray represents a ray defined by its origin and direction.
+// Time is the instant the ray exists at (within the shutter interval of the camera): the moving objects are
+// intersected at that instant, and the rays scattered from the ray keep it.
 type Ray struct {
 	Origin Point3
 	Dir    Vec3
 	Rnd    Rnd
+	Time   float64
 }
 
 // PointAt returns a new point along the ray.
@@ -177,6 +180,8 @@ type HitRecord struct {
 	Normal Vec3     // Normal at that point
 	Mat    Material // the material associated to this record
 	U, V   float64  // u, v surface coordinates of the ray hit point
+
+	VertexColor *Color // color interpolated from the vertices of a mesh (nil when the mesh has no colors)
 }
 
 // HitTable interface of objects that can be hit by a ray
@@ -234,7 +239,7 @@ func (hl HitTableList) BoundingBox(tm0, tm1 float64) (bool, *AABB) {
 		outputBox = *tmp
 	}
 	
-	return false, &outputBox
+	return true, &outputBox
 }
 
 // Len is part of sort.Interface.
@@ -285,11 +290,9 @@ func (hl *HitTableList) Less(i, j int) bool {
 		switch {
 		case less(p, q):
 			// p < q, so we have a decision.
-			fmt.Println("less(p, q)  return -1", p, q, k)
 			return true
 		case less(q, p):
 			// p > q, so we have a decision.
-			fmt.Println("less(q, p) return 1 : ", q, p, k)
 			return false
 		}
 		// p == q; try the next comparison.